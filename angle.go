@@ -0,0 +1,131 @@
+package makemkv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrAngleNotFound = errors.New("makemkv: angle not found")
+
+var angleInfoRegex = regexp.MustCompile(`(\d+)(?:\s*(?:/|of)\s*(\d+))?`)
+
+// parseAngleInfo parses ap_iaAngleInfo, which is the angle of the title and
+// sometimes the number of angles, like "2" or "2/3".
+func parseAngleInfo(s string) (angle int, count int) {
+	m := angleInfoRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0
+	}
+	angle, _ = strconv.Atoi(m[1])
+	count, _ = strconv.Atoi(m[2])
+	return angle, count
+}
+
+// parseSeamlessInfo tells if ap_iaSeamlessInfo says the title is made of
+// seamlessly branched segments.
+func parseSeamlessInfo(s string) bool {
+	s = strings.ToLower(s)
+	return s != "" && s != "0" && !strings.Contains(s, "non") && !strings.Contains(s, "not")
+}
+
+// AngleGroups groups the titles makemkvcon lists once per angle of the same
+// program: titles with an angle that share their source, or their length and
+// chapters when there is no source. Each group is sorted by angle and the
+// AngleCount of its titles is filled in if makemkvcon did not give it.
+// Titles without angles are left out.
+func AngleGroups(info DiscInfo) [][]TitleInfo {
+	type key struct {
+		source   string
+		duration int64
+		chapters int
+	}
+	var keys []key
+	groups := make(map[key][]int)
+	for i, t := range info.Titles {
+		if t.Angle == 0 {
+			continue
+		}
+		k := key{source: t.SourceFileName}
+		if k.source == "" {
+			k = key{duration: int64(t.Duration), chapters: t.ChapterCount}
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+
+	var result [][]TitleInfo
+	for _, k := range keys {
+		indices := groups[k]
+		count := 0
+		for _, i := range indices {
+			if info.Titles[i].Angle > count {
+				count = info.Titles[i].Angle
+			}
+		}
+		var group []TitleInfo
+		for _, i := range indices {
+			t := info.Titles[i]
+			if t.AngleCount == 0 {
+				t.AngleCount = count
+			}
+			group = append(group, t)
+		}
+		sort.SliceStable(group, func(a, b int) bool { return group[a].Angle < group[b].Angle })
+		result = append(result, group)
+	}
+	return result
+}
+
+// AngleTitle finds the title for an angle of the program of titleId.
+func AngleTitle(info DiscInfo, titleId int, angle int) (TitleInfo, error) {
+	for _, group := range AngleGroups(info) {
+		has := false
+		for _, t := range group {
+			has = has || t.Id == titleId
+		}
+		if !has {
+			continue
+		}
+		for _, t := range group {
+			if t.Angle == angle {
+				return t, nil
+			}
+		}
+		break
+	}
+	if angle == 1 && titleId >= 0 && titleId < len(info.Titles) && info.Titles[titleId].Angle == 0 {
+		// a title without angles is its only angle
+		return info.Titles[titleId], nil
+	}
+	return TitleInfo{}, fmt.Errorf("%w: angle %d of title %d", ErrAngleNotFound, angle, titleId)
+}
+
+// resolveAngle points the job at the title of j.Angle. makemkvcon has no
+// angle option, it lists every angle as a title of its own.
+func (j *MkvJob) resolveAngle(ctx context.Context) error {
+	if j.Angle == 0 {
+		return nil
+	}
+	id, err := strconv.Atoi(j.titleId)
+	if err != nil {
+		return fmt.Errorf("makemkv: an angle needs a single title, not %q", j.titleId)
+	}
+	if j.Info == nil {
+		if j.Info, err = Info(j.device, j.options).RunContext(ctx); err != nil {
+			return err
+		}
+	}
+	title, err := AngleTitle(*j.Info, id, j.Angle)
+	if err != nil {
+		return err
+	}
+	j.titleId = strconv.Itoa(title.Id)
+	return nil
+}
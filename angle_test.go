@@ -0,0 +1,59 @@
+package makemkv
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAngleGroups(t *testing.T) {
+	input := `TCOUNT:4
+TINFO:0,9,0,"1:45:00"
+TINFO:0,15,0,"1"
+TINFO:0,16,0,"00800.mpls"
+TINFO:0,36,0,"Seamless"
+TINFO:1,9,0,"0:05:00"
+TINFO:1,16,0,"00001.mpls"
+TINFO:2,9,0,"1:45:00"
+TINFO:2,15,0,"2"
+TINFO:2,16,0,"00800.mpls"
+TINFO:2,36,0,"Seamless"
+TINFO:3,9,0,"0:20:00"
+TINFO:3,15,0,"1/2"
+TINFO:3,16,0,"00900.mpls"
+`
+	info, err := parseDiscInfo(bufio.NewScanner(strings.NewReader(input)))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, info.Titles[2].Angle)
+	assert.True(t, info.Titles[2].Seamless)
+	assert.False(t, info.Titles[1].Seamless)
+	assert.Equal(t, 2, info.Titles[3].AngleCount)
+
+	groups := AngleGroups(info)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, []int{0, 2}, []int{groups[0][0].Id, groups[0][1].Id})
+	assert.Equal(t, 2, groups[0][0].AngleCount)
+
+	title, err := AngleTitle(info, 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, title.Id)
+	title, err = AngleTitle(info, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, title.Id)
+	_, err = AngleTitle(info, 3, 2)
+	assert.True(t, errors.Is(err, ErrAngleNotFound))
+
+	job := Mkv(nil, 0, t.TempDir(), MkvOptions{})
+	job.Info = &info
+	job.Angle = 2
+	assert.Nil(t, job.resolveAngle(context.Background()))
+	assert.Equal(t, "2", job.titleId)
+
+	job = MkvAll(nil, 0, t.TempDir(), MkvOptions{})
+	job.Angle = 2
+	assert.NotNil(t, job.resolveAngle(context.Background()))
+}
@@ -0,0 +1,132 @@
+package makemkv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ChannelLayout is a layout like makemkvcon's "5.1(side)" or "7.1".
+type ChannelLayout struct {
+	// as makemkvcon names it
+	Name string
+	// full range channels
+	Main int
+	LFE  int
+	// where the surround channels are, like "side" or "back", if named
+	Variant string
+}
+
+var namedLayouts = map[string]ChannelLayout{
+	"mono":   {Main: 1},
+	"stereo": {Main: 2},
+	"quad":   {Main: 4},
+}
+
+// ParseChannelLayout parses "mono", "stereo", "quad" and "N.M" layouts with
+// an optional variant in parentheses. Unknown layouts only have a Name.
+func ParseChannelLayout(s string) ChannelLayout {
+	s = strings.TrimSpace(s)
+	name := strings.ToLower(s)
+	variant := ""
+	if i := strings.Index(name, "("); i > 0 && strings.HasSuffix(name, ")") {
+		name, variant = name[:i], name[i+1:len(name)-1]
+	}
+	if l, ok := namedLayouts[name]; ok {
+		l.Name, l.Variant = s, variant
+		return l
+	}
+	l := ChannelLayout{Name: s, Variant: variant}
+	main, lfe, ok := strings.Cut(name, ".")
+	m, err1 := strconv.Atoi(main)
+	f, err2 := strconv.Atoi(lfe)
+	if !ok || err1 != nil || err2 != nil {
+		return ChannelLayout{Name: s}
+	}
+	l.Main, l.LFE = m, f
+	return l
+}
+
+func (l ChannelLayout) Channels() int {
+	return l.Main + l.LFE
+}
+
+func (l ChannelLayout) String() string {
+	return l.Name
+}
+
+// AudioOutput is what makemkvcon will write for an audio stream, from the
+// ap_iaOutput* attributes. It is empty when the stream is copied as is.
+type AudioOutput struct {
+	CodecShort     string
+	SampleRate     int
+	SampleSize     int
+	ChannelCount   int
+	ChannelLayout  ChannelLayout
+	MixDescription string
+}
+
+func (o *AudioOutput) set(attrId int, value string) {
+	switch attrId {
+	case ap_iaOutputCodecShort:
+		o.CodecShort = value
+	case ap_iaOutputAudioSampleRate:
+		o.SampleRate, _ = strconv.Atoi(value)
+	case ap_iaOutputAudioSampleSize:
+		o.SampleSize, _ = strconv.Atoi(value)
+	case ap_iaOutputAudioChannelsCount:
+		o.ChannelCount, _ = strconv.Atoi(value)
+	case ap_iaOutputAudioChannelLayoutName:
+		o.ChannelLayout = ParseChannelLayout(value)
+	case ap_iaOutputAudioMixDescription:
+		o.MixDescription = value
+	}
+}
+
+var outputCodecs = map[string]Codec{
+	"ac3":       CodecAC3,
+	"dd":        CodecAC3,
+	"e-ac3":     CodecEAC3,
+	"dd+":       CodecEAC3,
+	"dts":       CodecDTS,
+	"dts-hd ma": CodecDTSHD,
+	"truehd":    CodecTrueHD,
+	"lpcm":      CodecLPCM,
+	"pcm":       CodecLPCM,
+	"flac":      CodecFLAC,
+	"aac":       CodecAAC,
+	"mp2":       CodecMP2,
+	"mp3":       CodecMP3,
+}
+
+// OutputCodec is the codec that will be written, the stream codec if there
+// is no conversion.
+func (a AudioStreamInfo) OutputCodec() Codec {
+	if a.Output.CodecShort == "" {
+		return a.codec()
+	}
+	return outputCodecs[strings.ToLower(a.Output.CodecShort)]
+}
+
+// Converted tells if the stream will be written with another codec, like
+// LPCM that becomes FLAC.
+func (a AudioStreamInfo) Converted() bool {
+	if a.Output.CodecShort == "" {
+		return false
+	}
+	if strings.EqualFold(a.Output.CodecShort, a.CodecShort) {
+		return false
+	}
+	return a.OutputCodec() != a.codec() || a.OutputCodec() == CodecUnknown
+}
+
+// Downmixed tells if the stream will be written with fewer channels.
+func (a AudioStreamInfo) Downmixed() bool {
+	return a.Output.ChannelCount > 0 && a.Output.ChannelCount < a.ChannelCount
+}
+
+func (a AudioStreamInfo) codec() Codec {
+	if a.Codec != CodecUnknown {
+		return a.Codec
+	}
+	return ParseCodec(a.CodecId, a.CodecShort, a.CodecLong)
+}
@@ -0,0 +1,55 @@
+package makemkv
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChannelLayout(t *testing.T) {
+	assert.Equal(t, ChannelLayout{Name: "5.1(side)", Main: 5, LFE: 1, Variant: "side"}, ParseChannelLayout("5.1(side)"))
+	assert.Equal(t, ChannelLayout{Name: "7.1", Main: 7, LFE: 1}, ParseChannelLayout("7.1"))
+	assert.Equal(t, ChannelLayout{Name: "stereo", Main: 2}, ParseChannelLayout("stereo"))
+	assert.Equal(t, ChannelLayout{Name: "hexadecagonal"}, ParseChannelLayout("hexadecagonal"))
+}
+
+func TestAudioOutput(t *testing.T) {
+	input := `TCOUNT:1
+SINFO:0,0,1,6202,"Audio"
+SINFO:0,0,5,0,"A_LPCM"
+SINFO:0,0,6,0,"LPCM"
+SINFO:0,0,14,0,"6"
+SINFO:0,0,40,0,"5.1(side)"
+SINFO:0,0,41,0,"FLAC"
+SINFO:0,0,43,0,"48000"
+SINFO:0,0,44,0,"24"
+SINFO:0,0,45,0,"2"
+SINFO:0,0,46,0,"stereo"
+SINFO:0,0,48,0,"Downmix to stereo"
+SINFO:0,1,1,6202,"Audio"
+SINFO:0,1,5,0,"A_AC3"
+SINFO:0,1,6,0,"DD"
+SINFO:0,1,14,0,"6"
+`
+	info, err := parseDiscInfo(bufio.NewScanner(strings.NewReader(input)))
+	assert.Nil(t, err)
+	lpcm := info.Titles[0].AudioStreams[0]
+	assert.Equal(t, AudioOutput{
+		CodecShort:     "FLAC",
+		SampleRate:     48000,
+		SampleSize:     24,
+		ChannelCount:   2,
+		ChannelLayout:  ChannelLayout{Name: "stereo", Main: 2},
+		MixDescription: "Downmix to stereo",
+	}, lpcm.Output)
+	assert.Equal(t, CodecFLAC, lpcm.OutputCodec())
+	assert.True(t, lpcm.Converted())
+	assert.True(t, lpcm.Downmixed())
+
+	ac3 := info.Titles[0].AudioStreams[1]
+	assert.Equal(t, CodecAC3, ac3.OutputCodec())
+	assert.False(t, ac3.Converted())
+	assert.False(t, ac3.Downmixed())
+}
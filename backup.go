@@ -0,0 +1,120 @@
+package makemkv
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrBackupFailed   = errors.New("makemkv: backup failed")
+	ErrBackupHashFail = errors.New("makemkv: backup completed but hash check failed")
+)
+
+type BackupJob struct {
+	Statuschan  chan Status
+	Messagechan chan Message
+	Observer    Observer
+	device      Device
+	destination string
+	options     MkvOptions
+}
+
+type BackupResult struct {
+	Destination string
+	Messages    []Message
+}
+
+func Backup(device Device, destination string, opts MkvOptions) *BackupJob {
+	return &BackupJob{
+		Statuschan:  nil,
+		device:      device,
+		destination: destination,
+		options:     opts,
+	}
+}
+
+func (j *BackupJob) Run() (*BackupResult, error) {
+	return j.RunContext(context.Background())
+}
+
+func (j *BackupJob) RunContext(ctx context.Context) (*BackupResult, error) {
+	job := newObservedJob(JobBackup, j.device, "", j.destination)
+	if j.Observer != nil {
+		j.Observer.OnStart(job)
+	}
+	result, err := j.run(ctx, job)
+	if j.Observer != nil {
+		j.Observer.OnFinish(job, result, err)
+	}
+	return result, err
+}
+
+func (j *BackupJob) run(ctx context.Context, job ObservedJob) (*BackupResult, error) {
+	dev := DeviceString(j.device)
+	options := append(j.options.toStrings(), []string{"backup", dev, j.destination}...)
+	cmd := exec.CommandContext(ctx, j.options.executable(), options...)
+
+	var scanner bufio.Scanner
+	if out, err := cmd.StdoutPipe(); err != nil {
+		return nil, err
+	} else {
+		scanner = *bufio.NewScanner(out)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	result := &BackupResult{Destination: j.destination}
+	var backupErr error
+
+	var p progress
+	for scanner.Scan() {
+		line := scanner.Text()
+		prefix, content, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		switch prefix {
+		case "MSG":
+			msg, ok := parseMessage(content)
+			if !ok {
+				continue
+			}
+			result.Messages = append(result.Messages, msg)
+			if j.Observer != nil {
+				j.Observer.OnMessage(job, msg)
+			}
+			if j.Messagechan != nil {
+				j.Messagechan <- msg
+			}
+			switch msg.Code {
+			case msgAppBackupFailed:
+				backupErr = ErrBackupFailed
+			case msgAppBackupCompletedHashfail:
+				backupErr = ErrBackupHashFail
+			}
+		default:
+			if !p.update(prefix, strings.Split(content, ",")) {
+				continue
+			}
+			if j.Observer != nil {
+				j.Observer.OnProgress(job, p.status)
+			}
+			if j.Statuschan != nil {
+				j.Statuschan <- p.status
+			}
+		}
+	}
+
+	err := cmd.Wait()
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	} else if err != nil {
+		return result, newJobError(err, result.Messages)
+	}
+	return result, backupErr
+}
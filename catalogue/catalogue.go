@@ -0,0 +1,319 @@
+// Package catalogue keeps a history of scanned and ripped discs in a SQLite
+// database. The database is opened by the caller with the driver of their
+// choice, for example modernc.org/sqlite or github.com/mattn/go-sqlite3, so
+// the library does not depend on either.
+package catalogue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+)
+
+var ErrDiscNotFound = errors.New("catalogue: disc not found")
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS discs (
+		fingerprint TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		volume_name TEXT NOT NULL,
+		disc_type TEXT NOT NULL,
+		info TEXT NOT NULL,
+		first_seen INTEGER NOT NULL,
+		last_seen INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		fingerprint TEXT NOT NULL REFERENCES discs(fingerprint),
+		kind TEXT NOT NULL,
+		device TEXT NOT NULL,
+		title_id TEXT NOT NULL,
+		destination TEXT NOT NULL,
+		started INTEGER NOT NULL,
+		finished INTEGER NOT NULL,
+		state TEXT NOT NULL,
+		error TEXT NOT NULL,
+		class TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS jobs_fingerprint ON jobs(fingerprint)`,
+	`CREATE TABLE IF NOT EXISTS files (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_id INTEGER NOT NULL REFERENCES jobs(id),
+		fingerprint TEXT NOT NULL,
+		title_id INTEGER NOT NULL,
+		path TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		success INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		checksum TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS files_fingerprint ON files(fingerprint)`,
+	`CREATE TABLE IF NOT EXISTS errors (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_id INTEGER NOT NULL REFERENCES jobs(id),
+		fingerprint TEXT NOT NULL,
+		code INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		time INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS errors_fingerprint ON errors(fingerprint)`,
+}
+
+type Catalogue struct {
+	db *sql.DB
+}
+
+// Open creates the tables in db if they do not exist yet.
+func Open(ctx context.Context, db *sql.DB) (*Catalogue, error) {
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("catalogue: creating schema: %w", err)
+		}
+	}
+	return &Catalogue{db: db}, nil
+}
+
+type Disc struct {
+	Fingerprint string
+	Info        makemkv.DiscInfo
+	FirstSeen   time.Time
+	LastSeen    time.Time
+}
+
+// AddScan stores the latest snapshot of a disc and returns its fingerprint.
+func (c *Catalogue) AddScan(ctx context.Context, info makemkv.DiscInfo) (string, error) {
+	fingerprint := makemkv.Fingerprint(info)
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().Unix()
+	_, err = c.db.ExecContext(ctx, `INSERT INTO discs (fingerprint, name, volume_name, disc_type, info, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(fingerprint) DO UPDATE SET name = excluded.name, volume_name = excluded.volume_name,
+			disc_type = excluded.disc_type, info = excluded.info, last_seen = excluded.last_seen`,
+		fingerprint, info.Name, info.VolumeName, info.DiscType, string(data), now, now)
+	if err != nil {
+		return "", err
+	}
+	return fingerprint, nil
+}
+
+func (c *Catalogue) Disc(ctx context.Context, fingerprint string) (*Disc, error) {
+	var data string
+	var first, last int64
+	err := c.db.QueryRowContext(ctx, `SELECT info, first_seen, last_seen FROM discs WHERE fingerprint = ?`, fingerprint).
+		Scan(&data, &first, &last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDiscNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	disc := &Disc{Fingerprint: fingerprint, FirstSeen: time.Unix(first, 0), LastSeen: time.Unix(last, 0)}
+	if err := json.Unmarshal([]byte(data), &disc.Info); err != nil {
+		return nil, err
+	}
+	return disc, nil
+}
+
+// Rip is a finished rip of a disc that was added with AddScan.
+type Rip struct {
+	Fingerprint string
+	Device      string
+	TitleId     string
+	Destination string
+	Started     time.Time
+	Finished    time.Time
+	Result      *makemkv.MkvResult
+	Err         error
+	// sha256 of the output files by path, as computed by pipeline.Checksum
+	Checksums map[string]string
+}
+
+// RecordRip stores a rip with its output files and error messages.
+func (c *Catalogue) RecordRip(ctx context.Context, rip Rip) (int64, error) {
+	state, errText, class := makemkv.JobDone, "", makemkv.ClassNone
+	if rip.Err != nil {
+		state, errText, class = makemkv.JobFailed, rip.Err.Error(), makemkv.Classify(rip.Err)
+		if class == makemkv.ClassCancelled {
+			state = makemkv.JobCancelled
+		}
+	}
+	return c.recordJob(ctx, rip, state, errText, class)
+}
+
+func (c *Catalogue) recordJob(ctx context.Context, rip Rip, state makemkv.JobState, errText string, class makemkv.ErrorClass) (int64, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO jobs (fingerprint, kind, device, title_id, destination, started, finished, state, error, class)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rip.Fingerprint, string(makemkv.JobMkv), rip.Device, rip.TitleId, rip.Destination,
+		rip.Started.Unix(), rip.Finished.Unix(), string(state), errText, string(class))
+	if err != nil {
+		return 0, err
+	}
+	jobId, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if rip.Result != nil {
+		for _, f := range rip.Result.Files {
+			_, err := tx.ExecContext(ctx, `INSERT INTO files (job_id, fingerprint, title_id, path, size, success, error, checksum)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				jobId, rip.Fingerprint, f.TitleId, f.Path, f.Size, f.Success, f.Error, rip.Checksums[f.Path])
+			if err != nil {
+				return 0, err
+			}
+		}
+		for _, msg := range rip.Result.Messages {
+			if !msg.IsError() {
+				continue
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO errors (job_id, fingerprint, code, text, time) VALUES (?, ?, ?, ?, ?)`,
+				jobId, rip.Fingerprint, msg.Code, msg.Text, rip.Finished.Unix())
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return jobId, tx.Commit()
+}
+
+// Ripped reports whether a rip of the disc finished with at least one file
+// saved.
+func (c *Catalogue) Ripped(ctx context.Context, fingerprint string) (bool, error) {
+	var n int
+	err := c.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs
+		JOIN files ON files.job_id = jobs.id
+		WHERE jobs.fingerprint = ? AND jobs.state = ? AND files.success`,
+		fingerprint, string(makemkv.JobDone)).Scan(&n)
+	return n > 0, err
+}
+
+// TitleRipped reports whether a title of the disc was saved by a rip that
+// finished.
+func (c *Catalogue) TitleRipped(ctx context.Context, fingerprint string, titleId int) (bool, error) {
+	var n int
+	err := c.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs
+		JOIN files ON files.job_id = jobs.id
+		WHERE jobs.fingerprint = ? AND jobs.state = ? AND files.title_id = ? AND files.success`,
+		fingerprint, string(makemkv.JobDone), titleId).Scan(&n)
+	return n > 0, err
+}
+
+// allRipped reports whether a rip of all titles of the disc finished with at
+// least one file saved.
+func (c *Catalogue) allRipped(ctx context.Context, fingerprint string) (bool, error) {
+	var n int
+	err := c.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs
+		JOIN files ON files.job_id = jobs.id
+		WHERE jobs.fingerprint = ? AND jobs.state = ? AND jobs.title_id IN ('', 'all') AND files.success`,
+		fingerprint, string(makemkv.JobDone)).Scan(&n)
+	return n > 0, err
+}
+
+type File struct {
+	JobId    int64
+	TitleId  int
+	Path     string
+	Size     int64
+	Checksum string
+	Ripped   time.Time
+}
+
+// Files returns the files successfully ripped from the disc, newest first.
+func (c *Catalogue) Files(ctx context.Context, fingerprint string) ([]File, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT files.job_id, files.title_id, files.path, files.size, files.checksum, jobs.finished
+		FROM files JOIN jobs ON files.job_id = jobs.id
+		WHERE files.fingerprint = ? AND files.success
+		ORDER BY jobs.finished DESC, files.title_id`, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []File
+	for rows.Next() {
+		var f File
+		var finished int64
+		if err := rows.Scan(&f.JobId, &f.TitleId, &f.Path, &f.Size, &f.Checksum, &finished); err != nil {
+			return nil, err
+		}
+		f.Ripped = time.Unix(finished, 0)
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+type FailedTitle struct {
+	TitleId  int
+	Attempts int
+	// reason of the last failure
+	Error string
+}
+
+// FailedTitles returns the titles of the disc that failed and were never
+// ripped successfully afterwards.
+func (c *Catalogue) FailedTitles(ctx context.Context, fingerprint string) ([]FailedTitle, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT f.title_id, COUNT(*),
+			(SELECT l.error FROM files l WHERE l.fingerprint = f.fingerprint AND l.title_id = f.title_id AND NOT l.success
+				ORDER BY l.id DESC LIMIT 1)
+		FROM files f
+		WHERE f.fingerprint = ? AND NOT f.success
+			AND NOT EXISTS (SELECT 1 FROM files s WHERE s.fingerprint = f.fingerprint AND s.title_id = f.title_id AND s.success AND s.id > f.id)
+		GROUP BY f.title_id
+		ORDER BY f.title_id`, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []FailedTitle
+	for rows.Next() {
+		var t FailedTitle
+		if err := rows.Scan(&t.TitleId, &t.Attempts, &t.Error); err != nil {
+			return nil, err
+		}
+		titles = append(titles, t)
+	}
+	return titles, rows.Err()
+}
+
+type ErrorRecord struct {
+	JobId int64
+	Code  int
+	Text  string
+	Time  time.Time
+}
+
+// Errors returns the error messages makemkvcon reported for the disc,
+// oldest first.
+func (c *Catalogue) Errors(ctx context.Context, fingerprint string) ([]ErrorRecord, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT job_id, code, text, time FROM errors WHERE fingerprint = ? ORDER BY id`, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []ErrorRecord
+	for rows.Next() {
+		var r ErrorRecord
+		var t int64
+		if err := rows.Scan(&r.JobId, &r.Code, &r.Text, &t); err != nil {
+			return nil, err
+		}
+		r.Time = time.Unix(t, 0)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
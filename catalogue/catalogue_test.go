@@ -0,0 +1,167 @@
+//go:build sqlite
+
+// The catalogue tests run against github.com/mattn/go-sqlite3, which needs
+// cgo, so they only build with -tags sqlite.
+
+package catalogue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTest(t *testing.T) *Catalogue {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	// every connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	c, err := Open(context.Background(), db)
+	assert.Nil(t, err)
+	return c
+}
+
+func testDisc() makemkv.DiscInfo {
+	return makemkv.DiscInfo{
+		DiscType:   "Blu-ray disc",
+		Name:       "Movie",
+		VolumeName: "MOVIE_D1",
+		Titles: []makemkv.TitleInfo{
+			{Id: 0, Duration: 2 * time.Hour, SourceFileName: "00800.mpls"},
+			{Id: 1, Duration: 10 * time.Minute, SourceFileName: "00801.mpls"},
+		},
+	}
+}
+
+func TestSchema(t *testing.T) {
+	c := openTest(t)
+	// opening an existing catalogue keeps its tables
+	_, err := Open(context.Background(), c.db)
+	assert.Nil(t, err)
+
+	for _, table := range []string{"discs", "jobs", "files", "errors"} {
+		var name string
+		err := c.db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&name)
+		assert.Nil(t, err, table)
+	}
+}
+
+func TestAddScan(t *testing.T) {
+	c := openTest(t)
+	ctx := context.Background()
+
+	fingerprint, err := c.AddScan(ctx, testDisc())
+	assert.Nil(t, err)
+	assert.Equal(t, makemkv.Fingerprint(testDisc()), fingerprint)
+
+	// a later scan of the same disc replaces the snapshot
+	renamed := testDisc()
+	renamed.Name = "Other"
+	again, err := c.AddScan(ctx, renamed)
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, again)
+
+	disc, err := c.Disc(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.Equal(t, "Other", disc.Info.Name)
+	assert.Equal(t, 2, len(disc.Info.Titles))
+	assert.False(t, disc.LastSeen.Before(disc.FirstSeen))
+
+	_, err = c.Disc(ctx, "missing")
+	assert.Equal(t, ErrDiscNotFound, err)
+}
+
+func TestRecordRip(t *testing.T) {
+	c := openTest(t)
+	ctx := context.Background()
+	fingerprint, err := c.AddScan(ctx, testDisc())
+	assert.Nil(t, err)
+
+	ripped, err := c.Ripped(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.False(t, ripped)
+
+	started := time.Unix(1700000000, 0)
+	_, err = c.RecordRip(ctx, Rip{
+		Fingerprint: fingerprint,
+		Device:      "disc:0",
+		TitleId:     "all",
+		Destination: "/media",
+		Started:     started,
+		Finished:    started.Add(time.Hour),
+		Result: &makemkv.MkvResult{
+			Files: []makemkv.OutputFile{
+				{TitleId: 0, Path: "/media/Movie_t00.mkv", Size: 30 << 30, Success: true},
+				{TitleId: 1, Error: "read error"},
+			},
+			Messages: []makemkv.Message{
+				{Code: 2003, Flags: 516, Text: "Error reading sector"},
+				{Code: 5005, Text: "1 titles saved"},
+			},
+		},
+		Err:       &makemkv.JobError{Class: makemkv.ClassSource, Err: errors.New("exit status 1")},
+		Checksums: map[string]string{"/media/Movie_t00.mkv": "abc"},
+	})
+	assert.Nil(t, err)
+
+	var state, class string
+	assert.Nil(t, c.db.QueryRow(`SELECT state, class FROM jobs`).Scan(&state, &class))
+	assert.Equal(t, string(makemkv.JobFailed), state)
+	assert.Equal(t, string(makemkv.ClassSource), class)
+
+	// a failed job does not count as ripped
+	ripped, err = c.Ripped(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.False(t, ripped)
+
+	failed, err := c.FailedTitles(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.Equal(t, []FailedTitle{{TitleId: 1, Attempts: 1, Error: "read error"}}, failed)
+
+	records, err := c.Errors(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, 2003, records[0].Code)
+	assert.Equal(t, started.Add(time.Hour), records[0].Time)
+
+	// the retry of the failed title
+	jobId, err := c.RecordRip(ctx, Rip{
+		Fingerprint: fingerprint,
+		TitleId:     "1",
+		Started:     started.Add(2 * time.Hour),
+		Finished:    started.Add(3 * time.Hour),
+		Result: &makemkv.MkvResult{Files: []makemkv.OutputFile{
+			{TitleId: 1, Path: "/media/Movie_t01.mkv", Size: 1 << 30, Success: true},
+		}},
+	})
+	assert.Nil(t, err)
+
+	ripped, err = c.Ripped(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.True(t, ripped)
+	ripped, err = c.TitleRipped(ctx, fingerprint, 1)
+	assert.Nil(t, err)
+	assert.True(t, ripped)
+	ripped, err = c.TitleRipped(ctx, fingerprint, 0)
+	assert.Nil(t, err)
+	assert.False(t, ripped)
+
+	failed, err = c.FailedTitles(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.Empty(t, failed)
+
+	files, err := c.Files(ctx, fingerprint)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, File{JobId: jobId, TitleId: 1, Path: "/media/Movie_t01.mkv", Size: 1 << 30, Ripped: started.Add(3 * time.Hour)}, files[0])
+	assert.Equal(t, "/media/Movie_t00.mkv", files[1].Path)
+	assert.Equal(t, "abc", files[1].Checksum)
+}
@@ -0,0 +1,104 @@
+package catalogue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aravance/go-makemkv"
+)
+
+// SkipDuplicates can be used as makemkv.Queue.Skip. It skips mkv jobs for
+// titles that were already ripped from the disc, or for all titles when a
+// rip of all of them finished before. The disc is scanned first if the job
+// has no scan yet, the scan is kept on the job so the rip does not need
+// another.
+func (c *Catalogue) SkipDuplicates(ctx context.Context, job *makemkv.QueueJob) (bool, error) {
+	if job.Kind != makemkv.JobMkv {
+		return false, nil
+	}
+	if job.Info == nil {
+		device, err := makemkv.ParseDevice(job.Device)
+		if err != nil {
+			return false, err
+		}
+		if job.Info, err = makemkv.Info(device, job.Options).RunContext(ctx); err != nil {
+			return false, err
+		}
+	}
+	fingerprint, err := c.AddScan(ctx, *job.Info)
+	if err != nil {
+		return false, err
+	}
+	if job.TitleId == "" || job.TitleId == "all" {
+		return c.allRipped(ctx, fingerprint)
+	}
+	titleId, err := strconv.Atoi(job.TitleId)
+	if err != nil {
+		return false, fmt.Errorf("catalogue: invalid title id %q", job.TitleId)
+	}
+	if job.Angle != 0 {
+		// the files of an angle carry the id of the title makemkvcon lists
+		// for it
+		title, err := makemkv.AngleTitle(*job.Info, titleId, job.Angle)
+		if err != nil {
+			return false, err
+		}
+		titleId = title.Id
+	}
+	return c.TitleRipped(ctx, fingerprint, titleId)
+}
+
+// RecordQueueJob stores the scan and, for mkv jobs, the rip of a job that
+// finished in a makemkv.Queue, hashing the saved files for their checksums.
+// Jobs without a scan are ignored.
+func (c *Catalogue) RecordQueueJob(ctx context.Context, job makemkv.QueueJob) error {
+	if job.Info == nil {
+		return nil
+	}
+	fingerprint, err := c.AddScan(ctx, *job.Info)
+	if err != nil || job.Kind != makemkv.JobMkv || job.State == makemkv.JobSkipped {
+		return err
+	}
+
+	rip := Rip{
+		Fingerprint: fingerprint,
+		Device:      job.Device,
+		TitleId:     job.TitleId,
+		Destination: job.Destination,
+		Started:     job.Started,
+		Finished:    job.Finished,
+		Result:      job.Result,
+	}
+	if job.Result != nil {
+		rip.Checksums = make(map[string]string)
+		for _, f := range job.Result.Files {
+			if !f.Success {
+				continue
+			}
+			if rip.Checksums[f.Path], err = makemkv.HashFile(ctx, f.Path); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = c.recordJob(ctx, rip, job.State, job.Error, job.Class)
+	return err
+}
+
+// Attach makes q skip discs that were already ripped and record every job
+// it finishes, calling onError, if set, when that fails. It must be called
+// before q.Run.
+func (c *Catalogue) Attach(q *makemkv.Queue, onError func(err error)) {
+	q.Skip = c.SkipDuplicates
+	onDone := q.OnDone
+	q.OnDone = func(job makemkv.QueueJob) {
+		// no deadline, hashing the files takes as long as the disks need
+		if err := c.RecordQueueJob(context.Background(), job); err != nil && onError != nil {
+			onError(errors.Join(errors.New("catalogue: recording job "+job.Id), err))
+		}
+		if onDone != nil {
+			onDone(job)
+		}
+	}
+}
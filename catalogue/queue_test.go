@@ -0,0 +1,92 @@
+//go:build sqlite
+
+package catalogue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordQueueJob(t *testing.T) {
+	c := openTest(t)
+	ctx := context.Background()
+	info := testDisc()
+	path := filepath.Join(t.TempDir(), "Movie_t00.mkv")
+	assert.Nil(t, os.WriteFile(path, []byte("matroska"), 0644))
+
+	job := makemkv.QueueJob{
+		Kind:     makemkv.JobMkv,
+		Device:   "disc:0",
+		TitleId:  "0",
+		State:    makemkv.JobDone,
+		Started:  time.Now(),
+		Finished: time.Now(),
+		Info:     &info,
+		Result: &makemkv.MkvResult{Files: []makemkv.OutputFile{
+			{TitleId: 0, Path: path, Size: 8, Success: true},
+		}},
+	}
+	assert.Nil(t, c.RecordQueueJob(ctx, job))
+
+	files, err := c.Files(ctx, makemkv.Fingerprint(info))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+	// sha256 of "matroska"
+	assert.Equal(t, "e705b4c84eb1e8451121585b38a5d253a329fc37b1c989638e1a360bcd0910ac", files[0].Checksum)
+
+	job.State = makemkv.JobFailed
+	job.Error = "makemkv: insufficient disk space"
+	job.Class = makemkv.ClassDestination
+	job.Result = nil
+	assert.Nil(t, c.RecordQueueJob(ctx, job))
+	var class string
+	assert.Nil(t, c.db.QueryRow(`SELECT class FROM jobs WHERE state = ?`, string(makemkv.JobFailed)).Scan(&class))
+	assert.Equal(t, string(makemkv.ClassDestination), class)
+}
+
+func TestSkipDuplicates(t *testing.T) {
+	c := openTest(t)
+	ctx := context.Background()
+	info := testDisc()
+	fingerprint, err := c.AddScan(ctx, info)
+	assert.Nil(t, err)
+	_, err = c.RecordRip(ctx, Rip{
+		Fingerprint: fingerprint,
+		TitleId:     "0",
+		Result: &makemkv.MkvResult{Files: []makemkv.OutputFile{
+			{TitleId: 0, Path: "/media/Movie_t00.mkv", Success: true},
+		}},
+	})
+	assert.Nil(t, err)
+
+	skip := func(titleId string) bool {
+		skipped, err := c.SkipDuplicates(ctx, &makemkv.QueueJob{Kind: makemkv.JobMkv, TitleId: titleId, Info: &info})
+		assert.Nil(t, err)
+		return skipped
+	}
+	assert.True(t, skip("0"))
+	// another title of the same disc and a rip of all titles still run
+	assert.False(t, skip("1"))
+	assert.False(t, skip("all"))
+
+	_, err = c.RecordRip(ctx, Rip{
+		Fingerprint: fingerprint,
+		TitleId:     "all",
+		Result: &makemkv.MkvResult{Files: []makemkv.OutputFile{
+			{TitleId: 1, Path: "/media/Movie_t01.mkv", Success: true},
+		}},
+	})
+	assert.Nil(t, err)
+	assert.True(t, skip("all"))
+	assert.True(t, skip(""))
+
+	skipped, err := c.SkipDuplicates(ctx, &makemkv.QueueJob{Kind: makemkv.JobInfo, Info: &info})
+	assert.Nil(t, err)
+	assert.False(t, skipped)
+}
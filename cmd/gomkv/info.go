@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"gopkg.in/yaml.v3"
+)
+
+func runDrives(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("drives", flag.ContinueOnError)
+	format := flags.String("format", "table", "output format: table, json or yaml")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	drives, err := makemkv.ListDrives(ctx, makemkv.MkvOptions{})
+	if err != nil {
+		return err
+	}
+	return output(os.Stdout, *format, drives, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DEVICE\tPATH\tSTATE\tTYPE\tDRIVE\tDISC")
+		for _, d := range drives {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", makemkv.DeviceString(d.Device()), d.Path, d.State, d.DiscType(), d.DriveName, d.DiscName)
+		}
+		tw.Flush()
+	})
+}
+
+func runInfo(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	format := flags.String("format", "table", "output format: table, json or yaml")
+	opts := mkvFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	device, err := deviceArg(flags)
+	if err != nil {
+		return err
+	}
+
+	info, err := makemkv.Info(device, opts.options()).RunContext(ctx)
+	if err != nil {
+		return err
+	}
+	return output(os.Stdout, *format, info, func(w io.Writer) {
+		fmt.Fprintf(w, "%s (%s)\n", info.Name, info.DiscType)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TITLE\tDURATION\tCHAPTERS\tSIZE\tSOURCE\tVIDEO\tAUDIO\tSUBTITLES")
+		for _, t := range info.Titles {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\t%d\t%d\t%d\n", t.Id, t.Duration.Round(time.Second), t.ChapterCount,
+				makemkv.FormatSize(t.FileSize), t.SourceFileName, len(t.VideoStreams), len(t.AudioStreams), len(t.SubtitleStreams))
+		}
+		tw.Flush()
+	})
+}
+
+func output(w io.Writer, format string, v any, table func(w io.Writer)) error {
+	switch format {
+	case "table":
+		table(w)
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		defer enc.Close()
+		return enc.Encode(v)
+	default:
+		return fmt.Errorf("%w: unknown format %q", errUsage, format)
+	}
+}
+
+func deviceArg(flags *flag.FlagSet) (makemkv.Device, error) {
+	if flags.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "usage: gomkv %s [flags] <device>\n", flags.Name())
+		flags.PrintDefaults()
+		return nil, errUsage
+	}
+	return makemkv.ParseDevice(flags.Arg(0))
+}
+
+type optionFlags struct {
+	minlength *int
+	cache     *int
+	noscan    *bool
+	decrypt   *bool
+}
+
+func mkvFlags(flags *flag.FlagSet) *optionFlags {
+	return &optionFlags{
+		minlength: flags.Int("minlength", -1, "minimum title length in seconds"),
+		cache:     flags.Int("cache", 0, "read cache size in MB"),
+		noscan:    flags.Bool("noscan", false, "don't access media during disc scan"),
+		decrypt:   flags.Bool("decrypt", false, "decrypt stream files during backup"),
+	}
+}
+
+func (f *optionFlags) options() makemkv.MkvOptions {
+	var opts makemkv.MkvOptions
+	if *f.minlength >= 0 {
+		opts.Minlength = makemkv.Intopt(*f.minlength)
+	}
+	if *f.cache > 0 {
+		opts.Cache = makemkv.Intopt(*f.cache)
+	}
+	opts.Noscan = *f.noscan
+	opts.Decrypt = *f.decrypt
+	return opts
+}
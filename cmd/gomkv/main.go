@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/aravance/go-makemkv"
+)
+
+// exit codes, one per makemkv.ErrorClass so scripts can tell failures apart
+const (
+	exitOk          = 0
+	exitUnknown     = 1
+	exitUsage       = 2
+	exitTransient   = 3
+	exitSource      = 4
+	exitDestination = 5
+	exitLicense     = 6
+	exitCancelled   = 130
+)
+
+var errUsage = errors.New("usage")
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"drives", "list optical drives and inserted discs", runDrives},
+	{"info", "scan a disc and print its titles", runInfo},
+	{"rip", "rip titles to mkv files", runRip},
+	{"backup", "back up a disc to a folder", runBackup},
+	{"version", "print makemkvcon version and license state", runVersion},
+	{"watch", "print drive events as discs are inserted and removed", runWatch},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for _, cmd := range commands {
+		if cmd.name != os.Args[1] {
+			continue
+		}
+		err := cmd.run(ctx, os.Args[2:])
+		// a bare errUsage follows the usage the flag set already printed
+		if err != nil && err != errUsage {
+			fmt.Fprintln(os.Stderr, "gomkv:", err)
+		}
+		os.Exit(exitCode(err))
+	}
+
+	usage()
+	os.Exit(exitUsage)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gomkv <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return exitOk
+	}
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+	switch makemkv.Classify(err) {
+	case makemkv.ClassTransient:
+		return exitTransient
+	case makemkv.ClassSource:
+		return exitSource
+	case makemkv.ClassDestination:
+		return exitDestination
+	case makemkv.ClassLicense:
+		return exitLicense
+	case makemkv.ClassCancelled:
+		return exitCancelled
+	default:
+		return exitUnknown
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitOk},
+		{errUsage, exitUsage},
+		{fmt.Errorf("%w: invalid title %q", errUsage, "x"), exitUsage},
+		{&makemkv.JobError{Class: makemkv.ClassTransient, Err: &exec.ExitError{}}, exitTransient},
+		{&makemkv.JobError{Class: makemkv.ClassSource, Err: &exec.ExitError{}}, exitSource},
+		{&makemkv.InsufficientSpaceError{Path: "/out"}, exitDestination},
+		{makemkv.ErrKeyExpired, exitLicense},
+		{makemkv.ErrKeyIncorrect, exitLicense},
+		{context.Canceled, exitCancelled},
+		{errors.New("other"), exitUnknown},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, exitCode(test.err), fmt.Sprint(test.err))
+	}
+}
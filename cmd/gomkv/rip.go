@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aravance/go-makemkv"
+)
+
+func runRip(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("rip", flag.ContinueOnError)
+	titles := flags.String("title", "", "comma separated title ids to rip")
+	mainFeature := flags.Bool("main", false, "rip the main feature")
+	episodes := flags.Bool("episodes", false, "rip the titles that look like episodes")
+	angle := flags.Int("angle", 0, "angle to rip of multi-angle titles")
+	dest := flags.String("dest", ".", "destination folder")
+	overwrite := flags.Bool("overwrite", false, "replace existing files in the destination")
+	retries := flags.Int("retries", 1, "attempts per title on read errors, each retry toggles direct I/O and grows -cache")
+	ignoreReadErrors := flags.Bool("ignore-read-errors", false, "skip unreadable sectors on the last attempt")
+	quiet := flags.Bool("quiet", false, "don't show progress")
+	opts := mkvFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	device, err := deviceArg(flags)
+	if err != nil {
+		return err
+	}
+
+	info, err := makemkv.Info(device, opts.options()).RunContext(ctx)
+	if err != nil {
+		return err
+	}
+	selected, err := selectTitles(info, *titles, *mainFeature, *episodes)
+	if err != nil {
+		return err
+	}
+	if selected, err = selectAngle(info, selected, *angle); err != nil {
+		return err
+	}
+
+	for i, title := range selected {
+		job := makemkv.Mkv(device, title.Id, *dest, opts.options())
+		job.Info = info
+		job.Overwrite = *overwrite
+		job.Retry = retryPolicy(*retries, *ignoreReadErrors)
+
+		label := fmt.Sprintf("title %d (%d/%d)", title.Id, i+1, len(selected))
+		var bar *progressBar
+		if !*quiet {
+			job.Statuschan = make(chan makemkv.Status)
+			bar = newProgressBar(os.Stderr, label, job.Statuschan)
+		}
+		result, err := job.RunContext(ctx)
+		if bar != nil {
+			close(job.Statuschan)
+			bar.wait()
+		}
+		if err != nil {
+			return err
+		}
+		for _, f := range result.Files {
+			if f.Success {
+				fmt.Printf("%d\t%s\t%s\n", f.TitleId, makemkv.FormatSize(f.Size), f.Path)
+			} else {
+				return fmt.Errorf("title %d failed: %s", f.TitleId, f.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func selectTitles(info *makemkv.DiscInfo, ids string, mainFeature bool, episodes bool) ([]makemkv.TitleInfo, error) {
+	switch {
+	case mainFeature:
+		title, ok := makemkv.MainFeature(*info)
+		if !ok {
+			return nil, fmt.Errorf("disc has no titles")
+		}
+		return []makemkv.TitleInfo{title}, nil
+	case episodes:
+		titles := makemkv.Episodes(*info)
+		if len(titles) == 0 {
+			return nil, fmt.Errorf("no episodes found on disc")
+		}
+		return titles, nil
+	case ids != "":
+		var titles []makemkv.TitleInfo
+		for _, s := range strings.Split(ids, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || id < 0 || id >= len(info.Titles) {
+				return nil, fmt.Errorf("%w: invalid title %q", errUsage, s)
+			}
+			titles = append(titles, info.Titles[id])
+		}
+		return titles, nil
+	default:
+		return info.Titles, nil
+	}
+}
+
+func retryPolicy(attempts int, ignoreReadErrors bool) *makemkv.RetryPolicy {
+	if attempts < 2 {
+		return nil
+	}
+	policy := &makemkv.RetryPolicy{
+		MaxAttempts: attempts,
+		Escalations: []makemkv.Escalation{makemkv.ToggleDirectio(), makemkv.RaiseCache(256)},
+	}
+	if ignoreReadErrors {
+		policy.Escalations = append(policy.Escalations, makemkv.IgnoreReadErrors(attempts, ""))
+	}
+	return policy
+}
+
+// selectAngle swaps a single selected title for the title makemkvcon lists
+// for angle, it has no angle option of its own.
+func selectAngle(info *makemkv.DiscInfo, selected []makemkv.TitleInfo, angle int) ([]makemkv.TitleInfo, error) {
+	if angle == 0 {
+		return selected, nil
+	}
+	if len(selected) != 1 {
+		return nil, fmt.Errorf("%w: -angle needs a single title, %d selected", errUsage, len(selected))
+	}
+	title, err := makemkv.AngleTitle(*info, selected[0].Id, angle)
+	if err != nil {
+		return nil, err
+	}
+	return []makemkv.TitleInfo{title}, nil
+}
+
+func runBackup(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	dest := flags.String("dest", "", "destination folder")
+	quiet := flags.Bool("quiet", false, "don't show progress")
+	opts := mkvFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	device, err := deviceArg(flags)
+	if err != nil {
+		return err
+	}
+	if *dest == "" {
+		return fmt.Errorf("%w: -dest is required", errUsage)
+	}
+
+	job := makemkv.Backup(device, *dest, opts.options())
+	var bar *progressBar
+	if !*quiet {
+		job.Statuschan = make(chan makemkv.Status)
+		bar = newProgressBar(os.Stderr, "backup", job.Statuschan)
+	}
+	_, err = job.RunContext(ctx)
+	if bar != nil {
+		close(job.Statuschan)
+		bar.wait()
+	}
+	return err
+}
+
+type progressBar struct {
+	done chan struct{}
+}
+
+func newProgressBar(w io.Writer, label string, status <-chan makemkv.Status) *progressBar {
+	bar := &progressBar{done: make(chan struct{})}
+	go func() {
+		defer close(bar.done)
+		drawn := false
+		for s := range status {
+			if s.Max == 0 {
+				continue
+			}
+			const width = 30
+			filled := s.Total * width / s.Max
+			if filled > width {
+				filled = width
+			}
+			fmt.Fprintf(w, "\r%s [%s%s] %5.1f%% %-40.40s", label,
+				strings.Repeat("#", filled), strings.Repeat(".", width-filled),
+				float64(s.Total)*100/float64(s.Max), s.Channel)
+			drawn = true
+		}
+		if drawn {
+			fmt.Fprintln(w)
+		}
+	}()
+	return bar
+}
+
+func (b *progressBar) wait() {
+	<-b.done
+}
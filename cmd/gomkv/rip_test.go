@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectAngle(t *testing.T) {
+	// title 2 is the second angle of title 0
+	info := &makemkv.DiscInfo{Titles: []makemkv.TitleInfo{
+		{Id: 0, Duration: 2 * time.Hour, Angle: 1, SourceFileName: "00800.mpls"},
+		{Id: 1, Duration: 5 * time.Minute, SourceFileName: "00001.mpls"},
+		{Id: 2, Duration: 2 * time.Hour, Angle: 2, SourceFileName: "00800.mpls"},
+	}}
+
+	selected, err := selectTitles(info, "", true, false)
+	assert.Nil(t, err)
+	selected, err = selectAngle(info, selected, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(selected))
+	assert.Equal(t, 2, selected[0].Id)
+
+	selected, err = selectTitles(info, "1", false, false)
+	assert.Nil(t, err)
+	selected, err = selectAngle(info, selected, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, selected[0].Id)
+
+	selected, err = selectTitles(info, "0,1", false, false)
+	assert.Nil(t, err)
+	_, err = selectAngle(info, selected, 2)
+	assert.True(t, errors.Is(err, errUsage))
+	assert.Equal(t, exitUsage, exitCode(err))
+
+	_, err = selectAngle(info, []makemkv.TitleInfo{info.Titles[1]}, 2)
+	assert.True(t, errors.Is(err, makemkv.ErrAngleNotFound))
+
+	// without -angle the selection stays as it is
+	selected, err = selectAngle(info, info.Titles, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(selected))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	assert.Nil(t, retryPolicy(1, true))
+
+	// a retry changes the options even without -cache
+	policy := retryPolicy(3, false)
+	var opts makemkv.MkvOptions
+	for _, escalate := range policy.Escalations {
+		_, err := escalate(2, &opts)
+		assert.Nil(t, err)
+	}
+	assert.True(t, *opts.Directio)
+	assert.Nil(t, opts.Cache)
+	assert.Equal(t, 3, len(retryPolicy(3, true).Escalations))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+)
+
+func runVersion(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	format := flags.String("format", "table", "output format: table, json or yaml")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	license, err := makemkv.License(ctx, makemkv.MkvOptions{})
+	if err != nil {
+		return err
+	}
+	err = output(os.Stdout, *format, license, func(w io.Writer) {
+		v := license.Version
+		fmt.Fprintf(w, "%s %s %s(%s)\n", v.Name, v.Version, v.Platform, v.Build)
+		if license.LatestVersion != "" {
+			fmt.Fprintf(w, "latest version: %s\n", license.LatestVersion)
+		}
+		if license.KeyType != "" {
+			fmt.Fprintf(w, "key type: %s\n", license.KeyType)
+		}
+		if !license.KeyExpiration.IsZero() {
+			fmt.Fprintf(w, "key expires: %s\n", license.KeyExpiration.Format(time.DateOnly))
+		}
+		if license.Expired() {
+			fmt.Fprintln(w, "expired: yes")
+		}
+	})
+	if err == nil && license.Expired() {
+		return makemkv.ErrKeyExpired
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+)
+
+type driveEvent struct {
+	Time   time.Time
+	Event  string
+	Device string
+	Drive  makemkv.DriveInfo
+}
+
+func runWatch(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := flags.Duration("interval", 5*time.Second, "how often to poll the drives")
+	asJson := flags.Bool("json", false, "print events as JSON lines")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	emit := func(event string, d makemkv.DriveInfo) {
+		e := driveEvent{Time: time.Now(), Event: event, Device: makemkv.DeviceString(d.Device()), Drive: d}
+		if *asJson {
+			enc.Encode(e)
+		} else {
+			fmt.Printf("%s\t%s\t%s\t%s\n", e.Time.Format(time.RFC3339), e.Event, e.Device, d.DiscName)
+		}
+	}
+
+	known := make(map[int]makemkv.DriveInfo)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		drives, err := makemkv.ListDrives(ctx, makemkv.MkvOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, d := range drives {
+			prev, ok := known[d.Index]
+			switch {
+			case d.HasDisc() && (!ok || !prev.HasDisc() || prev.DiscName != d.DiscName):
+				emit("inserted", d)
+			case !d.HasDisc() && ok && prev.HasDisc():
+				emit("removed", d)
+			}
+			known[d.Index] = d
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
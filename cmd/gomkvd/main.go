@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/aravance/go-makemkv/metrics"
+	"github.com/aravance/go-makemkv/server"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8081", "address to serve the API on")
+	token := flag.String("token", os.Getenv("GOMKVD_TOKEN"), "API token, defaults to $GOMKVD_TOKEN")
+	executable := flag.String("makemkvcon", "makemkvcon", "makemkvcon binary")
+	metricsAddr := flag.String("metrics", "", "address to serve Prometheus metrics on, disabled if empty")
+	pollInterval := flag.Duration("poll", time.Minute, "how often to refresh drive and license metrics")
+	flag.Parse()
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "gomkvd: -token or $GOMKVD_TOKEN is required")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	observers := makemkv.Observers{makemkv.SlogObserver(slog.Default())}
+	s := server.New(*token)
+	s.Executable = *executable
+	if *metricsAddr != "" {
+		collector := metrics.New()
+		observers = append(observers, collector)
+		go pollMetrics(ctx, collector, makemkv.MkvOptions{Executable: *executable}, *pollInterval)
+		go func() {
+			err := http.ListenAndServe(*metricsAddr, collector)
+			fmt.Fprintln(os.Stderr, "gomkvd: metrics:", err)
+		}()
+	}
+	s.Observer = observers
+	srv := &http.Server{Addr: *listen, Handler: s}
+	go func() {
+		<-ctx.Done()
+		// cancelling the jobs ends their event streams, so Shutdown is not
+		// held up by connected dashboards
+		s.Close()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, "gomkvd:", err)
+		os.Exit(1)
+	}
+}
+
+func pollMetrics(ctx context.Context, collector *metrics.Collector, opts makemkv.MkvOptions, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if drives, err := makemkv.ListDrives(ctx, opts); err == nil {
+			collector.SetDrives(drives)
+		}
+		if license, err := makemkv.License(ctx, opts); err == nil {
+			collector.SetLicense(license)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package makemkv
+
+import "strings"
+
+type Codec string
+
+const (
+	CodecUnknown Codec = ""
+	CodecMPEG2   Codec = "mpeg2"
+	CodecH264    Codec = "h264"
+	CodecHEVC    Codec = "hevc"
+	CodecVC1     Codec = "vc1"
+	// the second view of a 3D H.264 video
+	CodecMVC    Codec = "mvc"
+	CodecAC3    Codec = "ac3"
+	CodecEAC3   Codec = "eac3"
+	CodecDTS    Codec = "dts"
+	CodecDTSHD  Codec = "dtshd-ma"
+	CodecTrueHD Codec = "truehd"
+	// TrueHD with Atmos objects
+	CodecAtmos  Codec = "atmos"
+	CodecLPCM   Codec = "lpcm"
+	CodecFLAC   Codec = "flac"
+	CodecAAC    Codec = "aac"
+	CodecMP2    Codec = "mp2"
+	CodecMP3    Codec = "mp3"
+	CodecPGS    Codec = "pgs"
+	CodecVobSub Codec = "vobsub"
+	// closed captions
+	CodecCC Codec = "cc"
+)
+
+type codecProps struct {
+	name        string
+	lossless    bool
+	lossy       bool
+	objectAudio bool
+	stereo3D    bool
+}
+
+var codecs = map[Codec]codecProps{
+	CodecMPEG2:  {name: "MPEG-2"},
+	CodecH264:   {name: "H.264"},
+	CodecHEVC:   {name: "HEVC"},
+	CodecVC1:    {name: "VC-1"},
+	CodecMVC:    {name: "MVC", stereo3D: true},
+	CodecAC3:    {name: "AC3", lossy: true},
+	CodecEAC3:   {name: "E-AC3", lossy: true},
+	CodecDTS:    {name: "DTS", lossy: true},
+	CodecDTSHD:  {name: "DTS-HD MA", lossless: true},
+	CodecTrueHD: {name: "TrueHD", lossless: true},
+	CodecAtmos:  {name: "TrueHD Atmos", lossless: true, objectAudio: true},
+	CodecLPCM:   {name: "LPCM", lossless: true},
+	CodecFLAC:   {name: "FLAC", lossless: true},
+	CodecAAC:    {name: "AAC", lossy: true},
+	CodecMP2:    {name: "MP2", lossy: true},
+	CodecMP3:    {name: "MP3", lossy: true},
+	CodecPGS:    {name: "PGS"},
+	CodecVobSub: {name: "VobSub"},
+	CodecCC:     {name: "CC"},
+}
+
+// ParseCodec finds the codec of the ap_iaCodecId, ap_iaCodecShort and
+// ap_iaCodecLong attributes of a stream. Codecs not in the list keep their
+// codec id, like Codec("A_OPUS"), and only a stream without an id is
+// CodecUnknown. Lossy DTS-HD variants are CodecDTS, their core.
+func ParseCodec(id string, short string, long string) Codec {
+	switch {
+	case id == "V_MPEG2", id == "V_MPEG-2":
+		return CodecMPEG2
+	case id == "V_MPEG4/ISO/AVC":
+		return CodecH264
+	case id == "V_MPEG4/ISO/MVC":
+		return CodecMVC
+	case id == "V_MPEGH/ISO/HEVC":
+		return CodecHEVC
+	case id == "V_MS/VFW/WVC1", id == "V_VC1":
+		return CodecVC1
+	case id == "A_AC3":
+		return CodecAC3
+	case id == "A_EAC3":
+		return CodecEAC3
+	case id == "A_DTS":
+		if strings.Contains(short, "MA") || strings.Contains(long, "Master Audio") {
+			return CodecDTSHD
+		}
+		return CodecDTS
+	case id == "A_TRUEHD":
+		if strings.Contains(short, "Atmos") || strings.Contains(long, "Atmos") {
+			return CodecAtmos
+		}
+		return CodecTrueHD
+	case id == "A_LPCM", strings.HasPrefix(id, "A_PCM"):
+		return CodecLPCM
+	case id == "A_FLAC":
+		return CodecFLAC
+	case strings.HasPrefix(id, "A_AAC"):
+		return CodecAAC
+	case id == "A_MPEG/L2":
+		return CodecMP2
+	case id == "A_MPEG/L3":
+		return CodecMP3
+	case id == "S_HDMV/PGS":
+		return CodecPGS
+	case id == "S_VOBSUB":
+		return CodecVobSub
+	case strings.HasPrefix(id, "S_CC"):
+		return CodecCC
+	}
+	return Codec(id)
+}
+
+// Known tells if the codec is one of the list rather than a codec id.
+func (c Codec) Known() bool {
+	_, ok := codecs[c]
+	return ok
+}
+
+// Name is the display name, the codec id for codecs not in the list and
+// "unknown" for CodecUnknown.
+func (c Codec) Name() string {
+	if p, ok := codecs[c]; ok {
+		return p.name
+	}
+	if c != CodecUnknown {
+		return string(c)
+	}
+	return "unknown"
+}
+
+func (c Codec) Lossless() bool {
+	return codecs[c].lossless
+}
+
+// Lossy is true for known lossy audio codecs only.
+func (c Codec) Lossy() bool {
+	return codecs[c].lossy
+}
+
+func (c Codec) ObjectAudio() bool {
+	return codecs[c].objectAudio
+}
+
+func (c Codec) Stereo3D() bool {
+	return codecs[c].stereo3D
+}
@@ -0,0 +1,46 @@
+package makemkv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCodec(t *testing.T) {
+	tests := []struct {
+		id, short, long string
+		codec           Codec
+	}{
+		{"V_MPEG4/ISO/AVC", "H264", "Mpeg4 AVC High@L4.1", CodecH264},
+		{"V_MPEGH/ISO/HEVC", "HEVC", "MpegH HEVC Main10@L5.1", CodecHEVC},
+		{"V_MPEG4/ISO/MVC", "MVC", "Mpeg4 MVC High@L4.1", CodecMVC},
+		{"A_DTS", "DTS-HD MA", "DTS-HD Master Audio", CodecDTSHD},
+		{"A_DTS", "DTS-HD HR", "DTS-HD High Resolution Audio", CodecDTS},
+		{"A_TRUEHD", "TrueHD", "TrueHD Atmos", CodecAtmos},
+		{"A_TRUEHD", "TrueHD", "Dolby TrueHD", CodecTrueHD},
+		{"A_LPCM", "LPCM", "LPCM", CodecLPCM},
+		{"S_HDMV/PGS", "PGS", "HDMV PGS Subtitles", CodecPGS},
+		{"A_AAC/MPEG4/LC", "AAC", "AAC", CodecAAC},
+		{"A_MPEG/L3", "MP3", "MP3", CodecMP3},
+		{"A_OPUS", "Opus", "Opus", Codec("A_OPUS")},
+		{"", "", "", CodecUnknown},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.codec, ParseCodec(tt.id, tt.short, tt.long), tt.long)
+	}
+
+	assert.True(t, CodecAtmos.Lossless())
+	assert.True(t, CodecAtmos.ObjectAudio())
+	assert.True(t, CodecEAC3.Lossy())
+	assert.True(t, CodecAAC.Lossy())
+	assert.True(t, CodecMP2.Lossy())
+	assert.False(t, CodecTrueHD.Lossy())
+	assert.False(t, Codec("A_OPUS").Lossy())
+	assert.False(t, Codec("A_OPUS").Known())
+	assert.Equal(t, "A_OPUS", Codec("A_OPUS").Name())
+	assert.False(t, CodecUnknown.Lossy())
+	assert.False(t, CodecUnknown.Lossless())
+	assert.True(t, CodecMVC.Stereo3D())
+	assert.Equal(t, "DTS-HD MA", CodecDTSHD.Name())
+	assert.Equal(t, "unknown", CodecUnknown.Name())
+}
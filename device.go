@@ -2,9 +2,11 @@ package makemkv
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Device interface {
@@ -13,10 +15,42 @@ type Device interface {
 	Available() bool
 }
 
+// DeviceString formats d the way makemkvcon expects a source, e.g. disc:0
+func DeviceString(d Device) string {
+	return d.Type() + ":" + d.Device()
+}
+
+func ParseDevice(s string) (Device, error) {
+	t, value, found := strings.Cut(s, ":")
+	if !found || value == "" {
+		return nil, fmt.Errorf("makemkv: invalid device %q", s)
+	}
+	switch t {
+	case "iso":
+		return NewIsoDevice(value), nil
+	case "file":
+		return NewFileDevice(value), nil
+	case "dev":
+		return NewDevDevice(strings.TrimPrefix(value, "/dev/")), nil
+	case "disc":
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("makemkv: invalid device %q", s)
+		}
+		return NewDiscDevice(id), nil
+	default:
+		return nil, fmt.Errorf("makemkv: unknown device type %q", t)
+	}
+}
+
 type IsoDevice struct {
 	path string
 }
 
+func NewIsoDevice(path string) *IsoDevice {
+	return &IsoDevice{path: path}
+}
+
 func (d *IsoDevice) Device() string {
 	return d.path
 }
@@ -34,6 +68,10 @@ type FileDevice struct {
 	path string
 }
 
+func NewFileDevice(path string) *FileDevice {
+	return &FileDevice{path: path}
+}
+
 func (d *FileDevice) Device() string {
 	return d.path
 }
@@ -51,12 +89,16 @@ type DevDevice struct {
 	device string
 }
 
+func NewDevDevice(device string) *DevDevice {
+	return &DevDevice{device: device}
+}
+
 func (d *DevDevice) Device() string {
 	return "/dev/" + d.device
 }
 
 func (d *DevDevice) Type() string {
-	return "disc"
+	return "dev"
 }
 
 func (d *DevDevice) Available() bool {
@@ -68,12 +110,16 @@ type DiscDevice struct {
 	id int
 }
 
+func NewDiscDevice(id int) *DiscDevice {
+	return &DiscDevice{id: id}
+}
+
 func (d *DiscDevice) Device() string {
 	return strconv.Itoa(d.id)
 }
 
 func (d *DiscDevice) Type() string {
-	return "dev"
+	return "disc"
 }
 
 func (d *DiscDevice) Available() bool {
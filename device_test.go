@@ -0,0 +1,37 @@
+package makemkv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceString(t *testing.T) {
+	assert.Equal(t, "disc:0", DeviceString(NewDiscDevice(0)))
+	assert.Equal(t, "dev:/dev/sr0", DeviceString(NewDevDevice("sr0")))
+	assert.Equal(t, "iso:/rips/movie.iso", DeviceString(NewIsoDevice("/rips/movie.iso")))
+	assert.Equal(t, "file:/rips/MOVIE", DeviceString(NewFileDevice("/rips/MOVIE")))
+}
+
+func TestParseDevice(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"disc:1", "disc:1"},
+		{"dev:sr0", "dev:/dev/sr0"},
+		{"dev:/dev/sr0", "dev:/dev/sr0"},
+		{"iso:/rips/movie.iso", "iso:/rips/movie.iso"},
+		{"file:/rips/MOVIE", "file:/rips/MOVIE"},
+	}
+	for _, test := range tests {
+		device, err := ParseDevice(test.input)
+		assert.Nil(t, err, test.input)
+		assert.Equal(t, test.expected, DeviceString(device), test.input)
+	}
+
+	for _, input := range []string{"", "disc", "disc:", "disc:sr0", "usb:0", "/dev/sr0"} {
+		_, err := ParseDevice(input)
+		assert.NotNil(t, err, input)
+	}
+}
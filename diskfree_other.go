@@ -0,0 +1,7 @@
+//go:build !(linux || darwin || freebsd)
+
+package makemkv
+
+func freeSpace(path string) (int64, error) {
+	return 0, errStatfsUnsupported
+}
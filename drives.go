@@ -0,0 +1,144 @@
+package makemkv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type DriveState int
+
+const (
+	DriveEmptyClosed DriveState = 0
+	DriveEmptyOpen   DriveState = 1
+	DriveInserted    DriveState = 2
+	DriveLoading     DriveState = 3
+	DriveNoDrive     DriveState = 256
+	DriveUnmounting  DriveState = 257
+)
+
+func (s DriveState) String() string {
+	switch s {
+	case DriveEmptyClosed:
+		return "empty"
+	case DriveEmptyOpen:
+		return "open"
+	case DriveInserted:
+		return "inserted"
+	case DriveLoading:
+		return "loading"
+	case DriveNoDrive:
+		return "none"
+	case DriveUnmounting:
+		return "unmounting"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	DiscFlagDvdFiles    = 1
+	DiscFlagHdvdFiles   = 2
+	DiscFlagBlurayFiles = 4
+	DiscFlagAacsFiles   = 8
+	DiscFlagBdsvmFiles  = 16
+)
+
+type DriveInfo struct {
+	Index     int
+	State     DriveState
+	Flags     int
+	DriveName string
+	DiscName  string
+	Path      string
+}
+
+func (d DriveInfo) HasDisc() bool {
+	return d.State == DriveInserted
+}
+
+func (d DriveInfo) Device() Device {
+	return NewDiscDevice(d.Index)
+}
+
+func (d DriveInfo) DiscType() string {
+	switch {
+	case d.Flags&DiscFlagBlurayFiles != 0:
+		return "bluray"
+	case d.Flags&DiscFlagHdvdFiles != 0:
+		return "hddvd"
+	case d.Flags&DiscFlagDvdFiles != 0:
+		return "dvd"
+	case d.HasDisc():
+		return "data"
+	default:
+		return ""
+	}
+}
+
+// ListDrives returns the drives makemkvcon knows about, skipping the empty
+// slots it reports for drives that do not exist. Only opts.Executable is
+// used, the scan always runs with a minimal cache.
+func ListDrives(ctx context.Context, opts MkvOptions) ([]DriveInfo, error) {
+	cmd := exec.CommandContext(ctx, opts.executable(), "-r", "--cache=1", "info", "disc:9999")
+	// makemkvcon exits non-zero since the drive does not exist
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(out) == 0 && err != nil {
+		return nil, err
+	}
+	return parseDrives(bufio.NewScanner(bytes.NewReader(out))), nil
+}
+
+func parseDrives(scanner *bufio.Scanner) []DriveInfo {
+	var drives []DriveInfo
+	seen := make(map[int]int)
+	for scanner.Scan() {
+		prefix, content, found := strings.Cut(scanner.Text(), ":")
+		if !found || prefix != "DRV" {
+			continue
+		}
+		drive, ok := parseDrive(content)
+		if !ok || drive.State == DriveNoDrive {
+			continue
+		}
+		// later lines update the state of a drive
+		if i, ok := seen[drive.Index]; ok {
+			drives[i] = drive
+			continue
+		}
+		seen[drive.Index] = len(drives)
+		drives = append(drives, drive)
+	}
+	return drives
+}
+
+// DRV:index,state,unknown,flags,drive name,disc name,device path
+func parseDrive(content string) (DriveInfo, bool) {
+	fields := splitFields(content)
+	if len(fields) < 6 {
+		return DriveInfo{}, false
+	}
+	var drive DriveInfo
+	var err error
+	if drive.Index, err = strconv.Atoi(fields[0]); err != nil {
+		return DriveInfo{}, false
+	}
+	state, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return DriveInfo{}, false
+	}
+	drive.State = DriveState(state)
+	drive.Flags, _ = strconv.Atoi(fields[3])
+	drive.DriveName = fields[4]
+	drive.DiscName = fields[5]
+	if len(fields) > 6 {
+		drive.Path = fields[6]
+	}
+	return drive, true
+}
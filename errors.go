@@ -0,0 +1,121 @@
+package makemkv
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+type ErrorClass string
+
+const (
+	ClassNone        ErrorClass = ""
+	ClassTransient   ErrorClass = "transient"
+	ClassSource      ErrorClass = "source"
+	ClassDestination ErrorClass = "destination"
+	ClassLicense     ErrorClass = "license"
+	ClassCancelled   ErrorClass = "cancelled"
+	ClassUnknown     ErrorClass = "unknown"
+)
+
+// JobError is returned when makemkvcon exits with an error. Class is derived
+// from the messages printed during the run.
+type JobError struct {
+	Class    ErrorClass
+	ExitCode int
+	Messages []Message
+	Err      error
+}
+
+func (e *JobError) Error() string {
+	if msg, ok := e.lastError(); ok {
+		return "makemkv: " + string(e.Class) + " error: " + msg.Text
+	}
+	return "makemkv: " + string(e.Class) + " error: " + e.Err.Error()
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+func (e *JobError) lastError() (Message, bool) {
+	for i := len(e.Messages) - 1; i >= 0; i-- {
+		if e.Messages[i].IsError() || classifyMessage(e.Messages[i]) != ClassNone {
+			return e.Messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+func newJobError(err error, messages []Message) error {
+	if err == nil {
+		return nil
+	}
+	jobErr := &JobError{
+		Class:    classifyMessages(messages),
+		ExitCode: -1,
+		Messages: messages,
+		Err:      err,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		jobErr.ExitCode = exitErr.ExitCode()
+	}
+	if jobErr.Class == ClassNone {
+		jobErr.Class = ClassUnknown
+	}
+	return jobErr
+}
+
+func Classify(err error) ErrorClass {
+	var jobErr *JobError
+	var destErr *DestinationError
+	var spaceErr *InsufficientSpaceError
+	var overwriteErr *OverwriteError
+	switch {
+	case err == nil:
+		return ClassNone
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ClassCancelled
+	case errors.As(err, &jobErr):
+		return jobErr.Class
+	case errors.As(err, &destErr), errors.As(err, &spaceErr), errors.As(err, &overwriteErr):
+		return ClassDestination
+	case errors.Is(err, ErrKeyIncorrect), errors.Is(err, ErrKeyNotSaved), errors.Is(err, ErrKeyExpired),
+		errors.Is(err, ErrInvalidKeyFormat):
+		return ClassLicense
+	default:
+		return ClassUnknown
+	}
+}
+
+// classifyMessages picks the most specific class of all messages, a license
+// problem explains a read failure better than the other way around.
+func classifyMessages(messages []Message) ErrorClass {
+	class := ClassNone
+	for _, msg := range messages {
+		switch c := classifyMessage(msg); c {
+		case ClassLicense, ClassDestination:
+			return c
+		case ClassNone:
+		default:
+			class = c
+		}
+	}
+	return class
+}
+
+func classifyMessage(msg Message) ErrorClass {
+	switch msg.Code {
+	case msgReadError:
+		return ClassTransient
+	case msgOpenDiscFailed, msgAppInitFailed:
+		return ClassSource
+	case msgAppFolderInvalid, msgSaveMkvFreeSpace:
+		return ClassDestination
+	case msgProtDemoKeyExpired:
+		return ClassLicense
+	default:
+		return ClassNone
+	}
+}
@@ -0,0 +1,62 @@
+package makemkv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fingerprint identifies a disc by the layout of its titles and streams,
+// which, unlike the names, is the same for every copy of a release.
+func Fingerprint(info DiscInfo) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\n", info.DiscType, info.VolumeName, len(info.Titles))
+	for _, t := range info.Titles {
+		segments := make([]string, len(t.Segments))
+		for i, s := range t.Segments {
+			segments[i] = strconv.Itoa(s)
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00%d\x00%s\n", t.SourceFileName, int64(t.Duration/time.Second), t.FileSize, t.ChapterCount, strings.Join(segments, ","))
+		for _, s := range t.VideoStreams {
+			fmt.Fprintf(h, "v\x00%s\x00%s\n", s.CodecId, s.VideoSize)
+		}
+		for _, s := range t.AudioStreams {
+			fmt.Fprintf(h, "a\x00%s\x00%s\x00%d\n", s.CodecId, s.LangCode, s.ChannelCount)
+		}
+		for _, s := range t.SubtitleStreams {
+			fmt.Fprintf(h, "s\x00%s\x00%s\n", s.CodecId, s.LangCode)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// HashFile returns the hex encoded sha256 of the file at path.
+func HashFile(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	buf := make([]byte, 1<<20)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := f.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,57 @@
+package makemkv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	info := DiscInfo{
+		DiscType:   "Blu-ray disc",
+		Name:       "Movie",
+		VolumeName: "MOVIE_D1",
+		Titles: []TitleInfo{{
+			Id:             0,
+			Name:           "Movie",
+			Duration:       2 * time.Hour,
+			FileSize:       30 << 30,
+			SourceFileName: "00800.mpls",
+			Segments:       []int{1, 2},
+			FileName:       "Movie_t00.mkv",
+			AudioStreams:   []AudioStreamInfo{{CodecId: "A_TRUEHD", LangCode: "eng", ChannelCount: 8}},
+		}},
+	}
+	fingerprint := Fingerprint(info)
+	assert.Equal(t, 32, len(fingerprint))
+
+	// names given by the user or makemkvcon do not change the fingerprint
+	renamed := info
+	renamed.Name = "Other"
+	renamed.Titles = []TitleInfo{info.Titles[0]}
+	renamed.Titles[0].Name = "Other"
+	renamed.Titles[0].FileName = "Other_t00.mkv"
+	assert.Equal(t, fingerprint, Fingerprint(renamed))
+
+	changed := info
+	changed.Titles = []TitleInfo{info.Titles[0]}
+	changed.Titles[0].AudioStreams = nil
+	assert.NotEqual(t, fingerprint, Fingerprint(changed))
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Movie_t00.mkv")
+	assert.Nil(t, os.WriteFile(path, []byte("matroska"), 0644))
+	sum, err := HashFile(context.Background(), path)
+	assert.Nil(t, err)
+	assert.Equal(t, "e705b4c84eb1e8451121585b38a5d253a329fc37b1c989638e1a360bcd0910ac", sum)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = HashFile(ctx, path)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -3,6 +3,7 @@ package makemkv
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"strconv"
@@ -11,8 +12,9 @@ import (
 )
 
 type InfoJob struct {
-	device  Device
-	options MkvOptions
+	Observer Observer
+	device   Device
+	options  MkvOptions
 }
 
 func Info(device Device, opts MkvOptions) *InfoJob {
@@ -29,6 +31,7 @@ type DiscInfo struct {
 	Name       string
 	LangCode   string
 	LangName   string
+	Language   Language
 	VolumeName string
 }
 
@@ -47,6 +50,10 @@ type TitleInfo struct {
 	FileName         string
 	MetadataLangCode string
 	MetadataLangName string
+	// angle of a multi-angle title, 0 if the title has no angles
+	Angle      int
+	AngleCount int
+	Seamless   bool
 }
 
 type VideoStreamInfo struct {
@@ -55,9 +62,16 @@ type VideoStreamInfo struct {
 	CodecId          string
 	CodecShort       string
 	CodecLong        string
+	Codec            Codec
 	VideoSize        string
 	AspectRatio      string
 	FrameRate        string
+	Width            int
+	Height           int
+	DisplayAspect    Rational
+	Fps              Rational
+	Interlaced       bool
+	HDR              HDRFormat
 	StreamFlags      int
 	MetadataLangCode string
 	MetadataLangName string
@@ -69,17 +83,22 @@ type AudioStreamInfo struct {
 	Name             string
 	LangCode         string
 	LangName         string
+	Language         Language
 	CodecId          string
 	CodecShort       string
 	CodecLong        string
+	Codec            Codec
 	BitRate          string
 	ChannelCount     int
+	ChannelLayout    ChannelLayout
 	SampleRate       int
 	SampleSize       int
 	StreamFlags      int
 	MetadataLangCode string
 	MetadataLangName string
 	ConversionType   string
+	// what makemkvcon will write for the stream
+	Output AudioOutput
 }
 
 type SubtitleStreamInfo struct {
@@ -87,9 +106,11 @@ type SubtitleStreamInfo struct {
 	Name             string
 	LangCode         string
 	LangName         string
+	Language         Language
 	CodecId          string
 	CodecShort       string
 	CodecLong        string
+	Codec            Codec
 	StreamFlags      int
 	MetadataLangCode string
 	MetadataLangName string
@@ -97,17 +118,75 @@ type SubtitleStreamInfo struct {
 }
 
 func (j *InfoJob) Run() (*DiscInfo, error) {
-	dev := j.device.Type() + ":" + j.device.Device()
+	return j.RunContext(context.Background())
+}
+
+func (j *InfoJob) RunContext(ctx context.Context) (*DiscInfo, error) {
+	job := newObservedJob(JobInfo, j.device, "", "")
+	if j.Observer != nil {
+		j.Observer.OnStart(job)
+	}
+	info, err := j.run(ctx, job)
+	if j.Observer != nil {
+		j.Observer.OnFinish(job, info, err)
+	}
+	return info, err
+}
+
+func (j *InfoJob) run(ctx context.Context, job ObservedJob) (*DiscInfo, error) {
+	dev := DeviceString(j.device)
 	options := append(j.options.toStrings(), []string{"info", dev}...)
-	cmd := exec.Command("makemkvcon", options...)
+	cmd := exec.CommandContext(ctx, j.options.executable(), options...)
 
-	out, err := cmd.Output()
-	if err != nil {
+	var scanner bufio.Scanner
+	if out, err := cmd.StdoutPipe(); err != nil {
+		return nil, err
+	} else {
+		scanner = *bufio.NewScanner(out)
+	}
+	if err := cmd.Start(); err != nil {
 		return nil, err
 	}
 
-	scanner := bufio.NewScanner(bytes.NewReader(out))
-	if discInfo, err := parseDiscInfo(scanner); err != nil {
+	// messages and progress reach the observer as makemkvcon prints them,
+	// the disc info is parsed once the scan is complete
+	var out bytes.Buffer
+	var messages []Message
+	var p progress
+	for scanner.Scan() {
+		line := scanner.Text()
+		out.WriteString(line)
+		out.WriteByte('\n')
+		prefix, content, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		switch prefix {
+		case "MSG":
+			msg, ok := parseMessage(content)
+			if !ok {
+				continue
+			}
+			messages = append(messages, msg)
+			if j.Observer != nil {
+				j.Observer.OnMessage(job, msg)
+			}
+		default:
+			if p.update(prefix, strings.Split(content, ",")) && j.Observer != nil {
+				j.Observer.OnProgress(job, p.status)
+			}
+		}
+	}
+
+	err := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else if err != nil {
+		return nil, newJobError(err, messages)
+	}
+
+	if discInfo, err := parseDiscInfo(bufio.NewScanner(&out)); err != nil {
 		return nil, err
 	} else {
 		return &discInfo, nil
@@ -152,8 +231,10 @@ func parseDiscInfo(scanner *bufio.Scanner) (DiscInfo, error) {
 				discInfo.Name = value
 			case ap_iaMetadataLanguageCode:
 				discInfo.LangCode = value
+				discInfo.Language = streamLanguage(discInfo.LangCode, discInfo.LangName)
 			case ap_iaMetadataLanguageName:
 				discInfo.LangName = value
+				discInfo.Language = streamLanguage(discInfo.LangCode, discInfo.LangName)
 			case ap_iaVolumeName:
 				discInfo.VolumeName = value
 			}
@@ -186,6 +267,10 @@ func parseDiscInfo(scanner *bufio.Scanner) (DiscInfo, error) {
 				}
 			case ap_iaOutputFileName:
 				discInfo.Titles[titleId].FileName = value
+			case ap_iaAngleInfo:
+				discInfo.Titles[titleId].Angle, discInfo.Titles[titleId].AngleCount = parseAngleInfo(value)
+			case ap_iaSeamlessInfo:
+				discInfo.Titles[titleId].Seamless = parseSeamlessInfo(value)
 			case ap_iaMetadataLanguageCode:
 				discInfo.Titles[titleId].MetadataLangCode = value
 			case ap_iaMetadataLanguageName:
@@ -206,7 +291,7 @@ func parseDiscInfo(scanner *bufio.Scanner) (DiscInfo, error) {
 				case "Audio":
 					i = len(discInfo.Titles[titleId].AudioStreams)
 					discInfo.Titles[titleId].AudioStreams = append(discInfo.Titles[titleId].AudioStreams, AudioStreamInfo{Id: streamId})
-				case "Subtitle":
+				case "Subtitle", "Subtitles":
 					i = len(discInfo.Titles[titleId].SubtitleStreams)
 					discInfo.Titles[titleId].SubtitleStreams = append(discInfo.Titles[titleId].SubtitleStreams, SubtitleStreamInfo{Id: streamId})
 				}
@@ -243,6 +328,11 @@ func parseDiscInfo(scanner *bufio.Scanner) (DiscInfo, error) {
 			case ap_iaAudioSampleSize:
 				i, _ := strconv.Atoi(value)
 				stream.setSampleSize(i)
+			case ap_iaAudioChannelLayoutName:
+				stream.setChannelLayout(value)
+			case ap_iaOutputCodecShort, ap_iaOutputAudioSampleRate, ap_iaOutputAudioSampleSize,
+				ap_iaOutputAudioChannelsCount, ap_iaOutputAudioChannelLayoutName, ap_iaOutputAudioMixDescription:
+				stream.setOutput(attrId, value)
 			case ap_iaVideoSize:
 				stream.setVideoSize(value)
 			case ap_iaVideoAspectRatio:
@@ -402,6 +492,19 @@ const (
 	ap_iaMaxValue
 )
 
+const (
+	StreamFlagDirectorsComments          = 1
+	StreamFlagAlternateDirectorsComments = 2
+	StreamFlagForVisuallyImpaired        = 4
+	StreamFlagCoreAudio                  = 256
+	StreamFlagSecondaryAudio             = 512
+	StreamFlagHasCoreAudio               = 1024
+	StreamFlagDerivedStream              = 2048
+	StreamFlagForcedSubtitles            = 4096
+	StreamFlagProfileSecondaryStream     = 16384
+	StreamFlagOffsetSequenceIdPresent    = 32768
+)
+
 //////////////////////////// hack ////////////////////////////
 // janky abstraction to simplify video/audio stream parsing //
 
@@ -416,7 +519,7 @@ func (t *TitleInfo) getStream(index streamIndex) iStreamInfo {
 		return &t.VideoStreams[index.i]
 	case "Audio":
 		return &t.AudioStreams[index.i]
-	case "Subtitle":
+	case "Subtitle", "Subtitles":
 		return &t.SubtitleStreams[index.i]
 	default:
 		return nil
@@ -435,6 +538,8 @@ type iStreamInfo interface {
 	setChannelCount(int)
 	setSampleRate(int)
 	setSampleSize(int)
+	setChannelLayout(string)
+	setOutput(int, string)
 	setVideoSize(string)
 	setAspectRatio(string)
 	setFrameRate(string)
@@ -450,6 +555,7 @@ func (v *VideoStreamInfo) setId(id int) {
 
 func (v *VideoStreamInfo) setName(name string) {
 	v.Name = name
+	v.HDR = detectHDR(v.Name, v.CodecLong)
 }
 
 func (v *VideoStreamInfo) setLangCode(langCode string) {
@@ -462,14 +568,18 @@ func (v *VideoStreamInfo) setLangName(langName string) {
 
 func (v *VideoStreamInfo) setCodecId(codecId string) {
 	v.CodecId = codecId
+	v.Codec = ParseCodec(v.CodecId, v.CodecShort, v.CodecLong)
 }
 
 func (v *VideoStreamInfo) setCodecShort(codecShort string) {
 	v.CodecShort = codecShort
+	v.Codec = ParseCodec(v.CodecId, v.CodecShort, v.CodecLong)
 }
 
 func (v *VideoStreamInfo) setCodecLong(codecLong string) {
 	v.CodecLong = codecLong
+	v.HDR = detectHDR(v.Name, v.CodecLong)
+	v.Codec = ParseCodec(v.CodecId, v.CodecShort, v.CodecLong)
 }
 
 func (v *VideoStreamInfo) setBitRate(bitRate string) {
@@ -488,16 +598,30 @@ func (v *VideoStreamInfo) setSampleSize(sampleSize int) {
 	// nop
 }
 
+func (v *VideoStreamInfo) setChannelLayout(channelLayout string) {
+	// nop
+}
+
+func (v *VideoStreamInfo) setOutput(attrId int, value string) {
+	// nop
+}
+
 func (v *VideoStreamInfo) setVideoSize(videoSize string) {
 	v.VideoSize = videoSize
+	var interlaced bool
+	v.Width, v.Height, interlaced = parseVideoSize(videoSize)
+	v.Interlaced = v.Interlaced || interlaced
 }
 
 func (v *VideoStreamInfo) setAspectRatio(aspectRatio string) {
 	v.AspectRatio = aspectRatio
+	v.DisplayAspect, _ = ParseRational(aspectRatio)
 }
 
 func (v *VideoStreamInfo) setFrameRate(frameRate string) {
 	v.FrameRate = frameRate
+	v.Fps, _ = ParseRational(frameRate)
+	v.Interlaced = v.Interlaced || strings.HasSuffix(strings.TrimSpace(frameRate), "i")
 }
 
 func (v *VideoStreamInfo) setStreamFlags(streamFlags int) {
@@ -526,22 +650,27 @@ func (a *AudioStreamInfo) setName(name string) {
 
 func (a *AudioStreamInfo) setLangCode(langCode string) {
 	a.LangCode = langCode
+	a.Language = streamLanguage(a.LangCode, a.LangName)
 }
 
 func (a *AudioStreamInfo) setLangName(langName string) {
 	a.LangName = langName
+	a.Language = streamLanguage(a.LangCode, a.LangName)
 }
 
 func (a *AudioStreamInfo) setCodecId(codecId string) {
 	a.CodecId = codecId
+	a.Codec = ParseCodec(a.CodecId, a.CodecShort, a.CodecLong)
 }
 
 func (a *AudioStreamInfo) setCodecShort(codecShort string) {
 	a.CodecShort = codecShort
+	a.Codec = ParseCodec(a.CodecId, a.CodecShort, a.CodecLong)
 }
 
 func (a *AudioStreamInfo) setCodecLong(codecLong string) {
 	a.CodecLong = codecLong
+	a.Codec = ParseCodec(a.CodecId, a.CodecShort, a.CodecLong)
 }
 
 func (a *AudioStreamInfo) setBitRate(bitRate string) {
@@ -560,6 +689,14 @@ func (a *AudioStreamInfo) setSampleSize(sampleSize int) {
 	a.SampleSize = sampleSize
 }
 
+func (a *AudioStreamInfo) setChannelLayout(channelLayout string) {
+	a.ChannelLayout = ParseChannelLayout(channelLayout)
+}
+
+func (a *AudioStreamInfo) setOutput(attrId int, value string) {
+	a.Output.set(attrId, value)
+}
+
 func (a *AudioStreamInfo) setVideoSize(videoSize string) {
 	// nop
 }
@@ -598,22 +735,27 @@ func (a *SubtitleStreamInfo) setName(name string) {
 
 func (a *SubtitleStreamInfo) setLangCode(langCode string) {
 	a.LangCode = langCode
+	a.Language = streamLanguage(a.LangCode, a.LangName)
 }
 
 func (a *SubtitleStreamInfo) setLangName(langName string) {
 	a.LangName = langName
+	a.Language = streamLanguage(a.LangCode, a.LangName)
 }
 
 func (a *SubtitleStreamInfo) setCodecId(codecId string) {
 	a.CodecId = codecId
+	a.Codec = ParseCodec(a.CodecId, a.CodecShort, a.CodecLong)
 }
 
 func (a *SubtitleStreamInfo) setCodecShort(codecShort string) {
 	a.CodecShort = codecShort
+	a.Codec = ParseCodec(a.CodecId, a.CodecShort, a.CodecLong)
 }
 
 func (a *SubtitleStreamInfo) setCodecLong(codecLong string) {
 	a.CodecLong = codecLong
+	a.Codec = ParseCodec(a.CodecId, a.CodecShort, a.CodecLong)
 }
 
 func (a *SubtitleStreamInfo) setBitRate(bitRate string) {
@@ -632,6 +774,14 @@ func (a *SubtitleStreamInfo) setSampleSize(sampleSize int) {
 	// nop
 }
 
+func (a *SubtitleStreamInfo) setChannelLayout(channelLayout string) {
+	// nop
+}
+
+func (a *SubtitleStreamInfo) setOutput(attrId int, value string) {
+	// nop
+}
+
 func (a *SubtitleStreamInfo) setVideoSize(videoSize string) {
 	// nop
 }
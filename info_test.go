@@ -2,6 +2,10 @@ package makemkv
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -17,6 +21,18 @@ func TestParseDiscInfo(t *testing.T) {
 	assert.Equal(t, "DiscName", result.Name)
 	assert.Equal(t, "LangCode", result.LangCode)
 	assert.Equal(t, "LangName", result.LangName)
+	assert.Equal(t, LanguageUndetermined, result.Language)
+	assert.Equal(t, Language("eng"), result.Titles[0].AudioStreams[0].Language)
+	assert.Equal(t, CodecAtmos, result.Titles[0].AudioStreams[0].Codec)
+	assert.Equal(t, CodecPGS, result.Titles[0].SubtitleStreams[0].Codec)
+	video := result.Titles[0].VideoStreams[0]
+	assert.Equal(t, 3840, video.Width)
+	assert.Equal(t, 2160, video.Height)
+	assert.Equal(t, Rational{16, 9}, video.DisplayAspect)
+	assert.Equal(t, Rational{24000, 1001}, video.Fps)
+	assert.Equal(t, ResolutionUHD, result.Titles[0].Resolution())
+	assert.Equal(t, 8, result.Titles[0].AudioStreams[0].ChannelLayout.Channels())
+	assert.Equal(t, "side", result.Titles[0].AudioStreams[1].ChannelLayout.Variant)
 	assert.Equal(t, "VolumeName", result.VolumeName)
 	assert.Equal(t, 3, len(result.Titles), "Titles length does not match")
 	assertTitle(t, TitleInfo{
@@ -63,9 +79,28 @@ func TestParseDiscInfo(t *testing.T) {
 	}, result.Titles[2])
 }
 
+// makemkvcon reports subtitle streams as "Subtitles", which used to be
+// dropped since only "Subtitle" was recognized
+func TestParseSubtitleStreamType(t *testing.T) {
+	for _, streamType := range []string{"Subtitles", "Subtitle"} {
+		input := "TCOUNT:1\nTINFO:0,2,0,\"Title\"\n" +
+			"SINFO:0,0,1,6203,\"" + streamType + "\"\n" +
+			"SINFO:0,0,3,0,\"fra\"\n" +
+			"SINFO:0,0,5,0,\"S_HDMV/PGS\"\n"
+		result, err := parseDiscInfo(bufio.NewScanner(strings.NewReader(input)))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(result.Titles[0].SubtitleStreams), streamType)
+		if len(result.Titles[0].SubtitleStreams) == 1 {
+			assert.Equal(t, "fra", result.Titles[0].SubtitleStreams[0].LangCode, streamType)
+			assert.Equal(t, "S_HDMV/PGS", result.Titles[0].SubtitleStreams[0].CodecId, streamType)
+		}
+	}
+}
+
 func assertTitle(t *testing.T, expected TitleInfo, actual TitleInfo) {
 	assert.Equal(t, len(expected.AudioStreams), len(actual.AudioStreams), "AudioStream length does not match")
 	assert.Equal(t, len(expected.VideoStreams), len(actual.VideoStreams), "VideoStream length does not match")
+	assert.Equal(t, len(expected.SubtitleStreams), len(actual.SubtitleStreams), "SubtitleStream length does not match")
 	assert.Equal(t, expected.Name, actual.Name)
 	assert.Equal(t, expected.ChapterCount, actual.ChapterCount)
 	assert.Equal(t, expected.Duration, actual.Duration)
@@ -309,3 +344,40 @@ SINFO:2,1,39,0,"Default"
 SINFO:2,1,40,0,"7.1"
 SINFO:2,1,42,5088,"ConversionType"
 `
+
+func TestParseDrives(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	drives := parseDrives(scanner)
+	assert.Equal(t, 1, len(drives))
+	assert.Equal(t, DriveInfo{
+		Index:     0,
+		State:     DriveInserted,
+		Flags:     12,
+		DriveName: "MyBluRayDrive",
+		DiscName:  "DiscLabel",
+		Path:      "/dev/sr0",
+	}, drives[0])
+	assert.True(t, drives[0].HasDisc())
+	assert.Equal(t, "bluray", drives[0].DiscType())
+}
+
+func TestInfoJobError(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "makemkvcon")
+	assert.Nil(t, os.WriteFile(bin, []byte(`#!/bin/sh
+echo 'MSG:5010,0,0,"Failed to open disc","Failed to open disc"'
+exit 1
+`), 0755))
+
+	_, err := Info(NewDiscDevice(0), MkvOptions{Executable: bin}).Run()
+	var jobErr *JobError
+	assert.True(t, errors.As(err, &jobErr))
+	assert.Equal(t, 1, jobErr.ExitCode)
+	assert.Equal(t, ClassSource, Classify(err))
+
+	assert.Nil(t, os.WriteFile(bin, []byte("#!/bin/sh\nexec sleep 10\n"), 0755))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = Info(NewDiscDevice(0), MkvOptions{Executable: bin}).RunContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, ClassCancelled, Classify(err))
+}
@@ -0,0 +1,345 @@
+package makemkv
+
+import (
+	"strings"
+	"sync"
+)
+
+// Language is an ISO 639-3 code. Every code of a language parses to the
+// same value, so "ger", "deu" and "de" are all equal.
+type Language string
+
+const LanguageUndetermined Language = "und"
+
+type languageEntry struct {
+	part1, part2B, part2T, part3 string
+	name                         string
+}
+
+// ISO 639-1, 639-2/B, 639-2/T and 639-3 codes and the english name, "-"
+// where a language has no code in a part. Covers all of ISO 639-1 and the
+// 639-2 and 639-3 languages found on discs.
+const languageTable = `
+aa aar aar aar Afar
+ab abk abk abk Abkhazian
+ae ave ave ave Avestan
+af afr afr afr Afrikaans
+ak aka aka aka Akan
+am amh amh amh Amharic
+an arg arg arg Aragonese
+ar ara ara ara Arabic
+as asm asm asm Assamese
+av ava ava ava Avaric
+ay aym aym aym Aymara
+az aze aze aze Azerbaijani
+ba bak bak bak Bashkir
+be bel bel bel Belarusian
+bg bul bul bul Bulgarian
+bi bis bis bis Bislama
+bm bam bam bam Bambara
+bn ben ben ben Bengali
+bo tib bod bod Tibetan
+br bre bre bre Breton
+bs bos bos bos Bosnian
+ca cat cat cat Catalan
+ce che che che Chechen
+ch cha cha cha Chamorro
+co cos cos cos Corsican
+cr cre cre cre Cree
+cs cze ces ces Czech
+cu chu chu chu Church Slavic
+cv chv chv chv Chuvash
+cy wel cym cym Welsh
+da dan dan dan Danish
+de ger deu deu German
+dv div div div Divehi
+dz dzo dzo dzo Dzongkha
+ee ewe ewe ewe Ewe
+el gre ell ell Greek
+en eng eng eng English
+eo epo epo epo Esperanto
+es spa spa spa Spanish
+et est est est Estonian
+eu baq eus eus Basque
+fa per fas fas Persian
+ff ful ful ful Fulah
+fi fin fin fin Finnish
+fj fij fij fij Fijian
+fo fao fao fao Faroese
+fr fre fra fra French
+fy fry fry fry Western Frisian
+ga gle gle gle Irish
+gd gla gla gla Gaelic
+gl glg glg glg Galician
+gn grn grn grn Guarani
+gu guj guj guj Gujarati
+gv glv glv glv Manx
+ha hau hau hau Hausa
+he heb heb heb Hebrew
+hi hin hin hin Hindi
+ho hmo hmo hmo Hiri Motu
+hr hrv hrv hrv Croatian
+ht hat hat hat Haitian
+hu hun hun hun Hungarian
+hy arm hye hye Armenian
+hz her her her Herero
+ia ina ina ina Interlingua
+id ind ind ind Indonesian
+ie ile ile ile Interlingue
+ig ibo ibo ibo Igbo
+ii iii iii iii Sichuan Yi
+ik ipk ipk ipk Inupiaq
+io ido ido ido Ido
+is ice isl isl Icelandic
+it ita ita ita Italian
+iu iku iku iku Inuktitut
+ja jpn jpn jpn Japanese
+jv jav jav jav Javanese
+ka geo kat kat Georgian
+kg kon kon kon Kongo
+ki kik kik kik Kikuyu
+kj kua kua kua Kuanyama
+kk kaz kaz kaz Kazakh
+kl kal kal kal Kalaallisut
+km khm khm khm Khmer
+kn kan kan kan Kannada
+ko kor kor kor Korean
+kr kau kau kau Kanuri
+ks kas kas kas Kashmiri
+ku kur kur kur Kurdish
+kv kom kom kom Komi
+kw cor cor cor Cornish
+ky kir kir kir Kirghiz
+la lat lat lat Latin
+lb ltz ltz ltz Luxembourgish
+lg lug lug lug Ganda
+li lim lim lim Limburgan
+ln lin lin lin Lingala
+lo lao lao lao Lao
+lt lit lit lit Lithuanian
+lu lub lub lub Luba-Katanga
+lv lav lav lav Latvian
+mg mlg mlg mlg Malagasy
+mh mah mah mah Marshallese
+mi mao mri mri Maori
+mk mac mkd mkd Macedonian
+ml mal mal mal Malayalam
+mn mon mon mon Mongolian
+mr mar mar mar Marathi
+ms may msa msa Malay
+mt mlt mlt mlt Maltese
+my bur mya mya Burmese
+na nau nau nau Nauru
+nb nob nob nob Norwegian Bokmål
+nd nde nde nde North Ndebele
+ne nep nep nep Nepali
+ng ndo ndo ndo Ndonga
+nl dut nld nld Dutch
+nn nno nno nno Norwegian Nynorsk
+no nor nor nor Norwegian
+nr nbl nbl nbl South Ndebele
+nv nav nav nav Navajo
+ny nya nya nya Chichewa
+oc oci oci oci Occitan
+oj oji oji oji Ojibwa
+om orm orm orm Oromo
+or ori ori ori Oriya
+os oss oss oss Ossetian
+pa pan pan pan Punjabi
+pi pli pli pli Pali
+pl pol pol pol Polish
+ps pus pus pus Pashto
+pt por por por Portuguese
+qu que que que Quechua
+rm roh roh roh Romansh
+rn run run run Rundi
+ro rum ron ron Romanian
+ru rus rus rus Russian
+rw kin kin kin Kinyarwanda
+sa san san san Sanskrit
+sc srd srd srd Sardinian
+sd snd snd snd Sindhi
+se sme sme sme Northern Sami
+sg sag sag sag Sango
+si sin sin sin Sinhala
+sk slo slk slk Slovak
+sl slv slv slv Slovenian
+sm smo smo smo Samoan
+sn sna sna sna Shona
+so som som som Somali
+sq alb sqi sqi Albanian
+sr srp srp srp Serbian
+ss ssw ssw ssw Swati
+st sot sot sot Southern Sotho
+su sun sun sun Sundanese
+sv swe swe swe Swedish
+sw swa swa swa Swahili
+ta tam tam tam Tamil
+te tel tel tel Telugu
+tg tgk tgk tgk Tajik
+th tha tha tha Thai
+ti tir tir tir Tigrinya
+tk tuk tuk tuk Turkmen
+tl tgl tgl tgl Tagalog
+tn tsn tsn tsn Tswana
+to ton ton ton Tonga
+tr tur tur tur Turkish
+ts tso tso tso Tsonga
+tt tat tat tat Tatar
+tw twi twi twi Twi
+ty tah tah tah Tahitian
+ug uig uig uig Uighur
+uk ukr ukr ukr Ukrainian
+ur urd urd urd Urdu
+uz uzb uzb uzb Uzbek
+ve ven ven ven Venda
+vi vie vie vie Vietnamese
+vo vol vol vol Volapük
+wa wln wln wln Walloon
+wo wol wol wol Wolof
+xh xho xho xho Xhosa
+yi yid yid yid Yiddish
+yo yor yor yor Yoruba
+za zha zha zha Zhuang
+zh chi zho zho Chinese
+zu zul zul zul Zulu
+- ast ast ast Asturian
+- ceb ceb ceb Cebuano
+- cnr cnr cnr Montenegrin
+- fil fil fil Filipino
+- gsw gsw gsw Swiss German
+- hak - hak Hakka Chinese
+- haw haw haw Hawaiian
+- hmn hmn hmn Hmong
+- nan - nan Min Nan Chinese
+- nds nds nds Low German
+- sco sco sco Scots
+- syr syr syr Syriac
+- tlh tlh tlh Klingon
+- - - cmn Mandarin Chinese
+- - - yue Cantonese
+- - - ase American Sign Language
+- mis mis mis Uncoded languages
+- mul mul mul Multiple languages
+- und und und Undetermined
+- zxx zxx zxx No linguistic content
+`
+
+var (
+	languagesOnce sync.Once
+	languages     map[Language]languageEntry
+	// every code and lower case name
+	languageIndex map[string]Language
+)
+
+func loadLanguages() {
+	languages = make(map[Language]languageEntry)
+	languageIndex = make(map[string]Language)
+	for _, line := range strings.Split(strings.TrimSpace(languageTable), "\n") {
+		f := strings.SplitN(line, " ", 5)
+		e := languageEntry{part1: f[0], part2B: f[1], part2T: f[2], part3: f[3], name: f[4]}
+		lang := Language(e.part3)
+		languages[lang] = e
+		for _, code := range f[:4] {
+			if code != "-" {
+				languageIndex[code] = lang
+			}
+		}
+		languageIndex[strings.ToLower(e.name)] = lang
+	}
+}
+
+// ParseLanguage finds the language of an ISO 639 code, a BCP-47 tag like
+// "pt-BR" or an english name like makemkvcon's LangName.
+func ParseLanguage(s string) (Language, bool) {
+	languagesOnce.Do(loadLanguages)
+	s = strings.ToLower(strings.TrimSpace(s))
+	if lang, ok := languageIndex[s]; ok {
+		return lang, true
+	}
+	if i := strings.IndexAny(s, "-_"); i > 0 {
+		lang, ok := languageIndex[s[:i]]
+		return lang, ok
+	}
+	return "", false
+}
+
+// streamLanguage is the language of a LangCode and LangName pair, the code
+// wins unless it is unknown or undetermined.
+func streamLanguage(code string, name string) Language {
+	lang, ok := ParseLanguage(code)
+	if ok && lang != LanguageUndetermined {
+		return lang
+	}
+	if byName, ok := ParseLanguage(name); ok {
+		return byName
+	}
+	if code != "" {
+		return LanguageUndetermined
+	}
+	return ""
+}
+
+func (l Language) entry() (languageEntry, bool) {
+	languagesOnce.Do(loadLanguages)
+	e, ok := languages[l]
+	return e, ok
+}
+
+func (l Language) code(part func(languageEntry) string) string {
+	if e, ok := l.entry(); ok && part(e) != "-" {
+		return part(e)
+	}
+	return ""
+}
+
+// ISO6391 is the two letter code, empty if there is none.
+func (l Language) ISO6391() string {
+	return l.code(func(e languageEntry) string { return e.part1 })
+}
+
+// ISO6392B is the bibliographic code, as in the Matroska Language element.
+func (l Language) ISO6392B() string {
+	return l.code(func(e languageEntry) string { return e.part2B })
+}
+
+func (l Language) ISO6392T() string {
+	return l.code(func(e languageEntry) string { return e.part2T })
+}
+
+func (l Language) ISO6393() string {
+	return l.code(func(e languageEntry) string { return e.part3 })
+}
+
+// BCP47 is the tag for the Matroska LanguageIETF element: the two letter code
+// if there is one, the three letter code otherwise.
+func (l Language) BCP47() string {
+	if part1 := l.ISO6391(); part1 != "" {
+		return part1
+	}
+	if l == "" {
+		return string(LanguageUndetermined)
+	}
+	return string(l)
+}
+
+// Name is the english name, or the code for unknown languages.
+func (l Language) Name() string {
+	if e, ok := l.entry(); ok {
+		return e.name
+	}
+	return string(l)
+}
+
+func (l Language) String() string {
+	return string(l)
+}
+
+// Matches tells if code or name is l, with all codes of a language matching.
+func (l Language) Matches(code string) bool {
+	other, ok := ParseLanguage(code)
+	if !ok {
+		return strings.EqualFold(string(l), code)
+	}
+	return other == l
+}
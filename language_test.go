@@ -0,0 +1,45 @@
+package makemkv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLanguage(t *testing.T) {
+	for _, s := range []string{"ger", "deu", "de", "DE", "German", "de-AT"} {
+		lang, ok := ParseLanguage(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, Language("deu"), lang, s)
+	}
+	_, ok := ParseLanguage("xx")
+	assert.False(t, ok)
+
+	lang, _ := ParseLanguage("fre")
+	assert.Equal(t, "fr", lang.BCP47())
+	assert.Equal(t, "fre", lang.ISO6392B())
+	assert.Equal(t, "fra", lang.ISO6392T())
+	assert.Equal(t, "French", lang.Name())
+	assert.True(t, lang.Matches("fra"))
+	assert.False(t, lang.Matches("eng"))
+
+	lang, _ = ParseLanguage("yue")
+	assert.Equal(t, "yue", lang.BCP47())
+	assert.Equal(t, "", lang.ISO6392B())
+	assert.Equal(t, "und", Language("").BCP47())
+}
+
+func TestStreamLanguage(t *testing.T) {
+	assert.Equal(t, Language("nld"), streamLanguage("dut", "Dutch"))
+	assert.Equal(t, Language("spa"), streamLanguage("und", "Spanish"))
+	assert.Equal(t, Language("spa"), streamLanguage("esl", "Spanish"))
+	assert.Equal(t, LanguageUndetermined, streamLanguage("xyz", "Elvish"))
+	assert.Equal(t, Language(""), streamLanguage("", ""))
+
+	title := TitleInfo{AudioStreams: []AudioStreamInfo{
+		{Id: 1, LangCode: "deu", CodecId: "A_AC3"},
+		{Id: 2, LangCode: "eng", CodecId: "A_AC3"},
+	}}
+	s := SelectTracks(title, TrackPreference{Audio: []TrackRule{{Lang: "ger"}}})
+	assert.Equal(t, []int{1}, s.Ids())
+}
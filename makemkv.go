@@ -1,6 +1,7 @@
 package makemkv
 
 import (
+	"fmt"
 	"strconv"
 )
 
@@ -12,15 +13,50 @@ type Status struct {
 	Max     int
 }
 
+type progress struct {
+	status Status
+}
+
+// update tracks the PRGT/PRGC/PRGV lines and reports whether the line was a
+// progress value that should be sent to the status channel.
+func (p *progress) update(prefix string, parts []string) bool {
+	if len(parts) < 3 {
+		return false
+	}
+	switch prefix {
+	case "PRGT":
+		p.status.Title = parts[2]
+	case "PRGC":
+		p.status.Channel = parts[2]
+	case "PRGV":
+		p.status.Current, _ = strconv.Atoi(parts[0])
+		p.status.Total, _ = strconv.Atoi(parts[1])
+		p.status.Max, _ = strconv.Atoi(parts[2])
+		return true
+	}
+	return false
+}
+
 type MkvOptions struct {
-	Messages  *string
-	Progress  *string
-	Debug     *string
-	Directio  *bool
-	Cache     *int
-	Minlength *int
-	Noscan    bool
-	Decrypt   bool
+	// the makemkvcon binary, looked up in PATH unless it contains a path
+	// separator. Empty runs "makemkvcon". It is not part of a job's JSON so
+	// a stored or submitted job cannot pick the binary that runs it.
+	Executable string `json:"-"`
+	Messages   *string
+	Progress   *string
+	Debug      *string
+	Directio   *bool
+	Cache      *int
+	Minlength  *int
+	Noscan     bool
+	Decrypt    bool
+}
+
+func (m MkvOptions) executable() string {
+	if m.Executable == "" {
+		return "makemkvcon"
+	}
+	return m.Executable
 }
 
 func (m MkvOptions) toStrings() []string {
@@ -52,6 +88,20 @@ func (m MkvOptions) toStrings() []string {
 	return result
 }
 
+// FormatSize prints a byte count in binary units, like "1.5 GiB".
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 func Stropt(s string) *string {
 	return &s
 }
@@ -59,3 +109,7 @@ func Stropt(s string) *string {
 func Intopt(i int) *int {
 	return &i
 }
+
+func Boolopt(b bool) *bool {
+	return &b
+}
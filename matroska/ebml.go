@@ -0,0 +1,169 @@
+package matroska
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+var (
+	ErrInvalidVint = errors.New("matroska: invalid variable size integer")
+	ErrNotMatroska = errors.New("matroska: not a matroska file")
+)
+
+// sizes of this value mean the element runs until its parent ends
+const unknownSize int64 = -1
+
+type element struct {
+	id         uint32
+	offset     int64
+	dataOffset int64
+	size       int64
+}
+
+func (e element) end() int64 {
+	return e.dataOffset + e.size
+}
+
+// readVint reads an EBML variable size integer. With keepMarker set the
+// length marker bit is kept, which is how element ids are written.
+func readVint(r io.Reader, keepMarker bool) (uint64, int, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return 0, 0, err
+	}
+	length := 1
+	for mask := byte(0x80); length <= 8 && b[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, 0, ErrInvalidVint
+	}
+	if length > 1 {
+		if _, err := io.ReadFull(r, b[1:length]); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	value := uint64(b[0])
+	if !keepMarker {
+		value &= uint64(0xff >> length)
+	}
+	for i := 1; i < length; i++ {
+		value = value<<8 | uint64(b[i])
+	}
+	return value, length, nil
+}
+
+func readElementHeader(r io.ReadSeeker) (element, error) {
+	offset, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return element{}, err
+	}
+	id, idLen, err := readVint(r, true)
+	if err != nil {
+		return element{}, err
+	}
+	if idLen > 4 {
+		return element{}, ErrInvalidVint
+	}
+	size, sizeLen, err := readVint(r, false)
+	if err != nil {
+		return element{}, err
+	}
+
+	e := element{
+		id:         uint32(id),
+		offset:     offset,
+		dataOffset: offset + int64(idLen) + int64(sizeLen),
+		size:       int64(size),
+	}
+	// all ones means unknown size
+	if size == uint64(1)<<(7*sizeLen)-1 {
+		e.size = unknownSize
+	}
+	return e, nil
+}
+
+func readData(r io.ReadSeeker, e element) ([]byte, error) {
+	if e.size < 0 || e.size > 1<<24 {
+		return nil, ErrInvalidVint
+	}
+	if _, err := r.Seek(e.dataOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, e.size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func readUint(r io.ReadSeeker, e element) (uint64, error) {
+	data, err := readData(r, e)
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value, nil
+}
+
+func readFloat(r io.ReadSeeker, e element) (float64, error) {
+	data, err := readData(r, e)
+	if err != nil {
+		return 0, err
+	}
+	switch len(data) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	default:
+		return 0, ErrInvalidVint
+	}
+}
+
+func readString(r io.ReadSeeker, e element) (string, error) {
+	data, err := readData(r, e)
+	if err != nil {
+		return "", err
+	}
+	// strings may be zero padded
+	for len(data) > 0 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	return string(data), nil
+}
+
+// children calls fn for every child of parent. fn is responsible for reading
+// the child, children takes care of seeking to the next one.
+func children(r io.ReadSeeker, parent element, fn func(e element) error) error {
+	pos := parent.dataOffset
+	for parent.size == unknownSize || pos < parent.end() {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		e, err := readElementHeader(r)
+		if err == io.EOF && parent.size == unknownSize {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if e.size == unknownSize {
+			// only clusters are written like this in practice, and nothing
+			// we care about follows a live cluster
+			return nil
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+		pos = e.end()
+	}
+	return nil
+}
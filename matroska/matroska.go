@@ -0,0 +1,544 @@
+package matroska
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	idEBML           = 0x1A45DFA3
+	idDocType        = 0x4282
+	idSegment        = 0x18538067
+	idInfo           = 0x1549A966
+	idTimestampScale = 0x2AD7B1
+	idDuration       = 0x4489
+	idTitle          = 0x7BA9
+	idMuxingApp      = 0x4D80
+	idWritingApp     = 0x5741
+	idTracks         = 0x1654AE6B
+	idTrackEntry     = 0xAE
+	idTrackNumber    = 0xD7
+	idTrackUID       = 0x73C5
+	idTrackType      = 0x83
+	idFlagEnabled    = 0xB9
+	idFlagDefault    = 0x88
+	idFlagForced     = 0x55AA
+	idFlagVisualImp  = 0x55AC
+	idFlagCommentary = 0x55AF
+	idName           = 0x536E
+	idLanguage       = 0x22B59C
+	idLanguageIETF   = 0x22B59D
+	idCodecID        = 0x86
+	idVideo          = 0xE0
+	idPixelWidth     = 0xB0
+	idPixelHeight    = 0xBA
+	idAudio          = 0xE1
+	idSamplingFreq   = 0xB5
+	idChannels       = 0x9F
+	idBitDepth       = 0x6264
+	idChapters       = 0x1043A770
+	idEditionEntry   = 0x45B9
+	idChapterAtom    = 0xB6
+	idChapterStart   = 0x91
+	idChapterEnd     = 0x92
+	idChapterDisplay = 0x80
+	idChapString     = 0x85
+	idChapLanguage   = 0x437C
+	idCluster        = 0x1F43B675
+	idVoid           = 0xEC
+	idSeekHead       = 0x114D9B74
+	idSeek           = 0x4DBB
+	idSeekID         = 0x53AB
+	idSeekPosition   = 0x53AC
+	idDefaultDur     = 0x23E383
+	idDisplayWidth   = 0x54B0
+	idDisplayHeight  = 0x54BA
+	idFlagInterlaced = 0x9A
+	idTags           = 0x1254C367
+	idTag            = 0x7373
+	idTargets        = 0x63C0
+	idTargetTypeVal  = 0x68CA
+	idTargetType     = 0x63CA
+	idTagTrackUID    = 0x63C5
+	idSimpleTag      = 0x67C8
+	idTagName        = 0x45A3
+	idTagLanguage    = 0x447A
+	idTagDefault     = 0x4484
+	idTagString      = 0x4487
+	idAttachments    = 0x1941A469
+	idAttachedFile   = 0x61A7
+	idFileDesc       = 0x467E
+	idFileName       = 0x466E
+	idFileMediaType  = 0x4660
+	idFileData       = 0x465C
+	idFileUID        = 0x46AE
+)
+
+type TrackType int
+
+const (
+	TrackVideo    TrackType = 1
+	TrackAudio    TrackType = 2
+	TrackSubtitle TrackType = 17
+)
+
+type File struct {
+	Title       string
+	Duration    time.Duration
+	MuxingApp   string
+	WritingApp  string
+	Tracks      []Track
+	Chapters    []Chapter
+	Tags        []Tag
+	Attachments []Attachment
+	// the segment claims more data than the file holds
+	Truncated bool
+	Size      int64
+}
+
+type Track struct {
+	Number       int
+	UID          uint64
+	Type         TrackType
+	Name         string
+	Language     string
+	LanguageIETF string
+	CodecId      string
+	Enabled      bool
+	Default      bool
+	Forced       bool
+	// audio description for the visually impaired
+	VisualImpaired bool
+	Commentary     bool
+
+	DefaultDuration time.Duration
+
+	PixelWidth    int
+	PixelHeight   int
+	DisplayWidth  int
+	DisplayHeight int
+	Interlaced    bool
+
+	SampleRate   float64
+	ChannelCount int
+	SampleSize   int
+}
+
+type Tag struct {
+	TargetType      string
+	TargetTypeValue int
+	TrackUIDs       []uint64
+	SimpleTags      []SimpleTag
+}
+
+type SimpleTag struct {
+	Name     string
+	Value    string
+	Language string
+	Children []SimpleTag
+}
+
+// Attachment describes an attached file, the data itself is not loaded but
+// can be read from DataOffset.
+type Attachment struct {
+	UID         uint64
+	Name        string
+	Description string
+	MediaType   string
+	Size        int64
+	DataOffset  int64
+}
+
+type Chapter struct {
+	Start    time.Duration
+	End      time.Duration
+	Name     string
+	Language string
+}
+
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+func Read(r io.ReadSeeker) (*File, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	header, err := readElementHeader(r)
+	if err != nil || header.id != idEBML {
+		return nil, ErrNotMatroska
+	}
+	var docType string
+	err = children(r, header, func(e element) error {
+		if e.id == idDocType {
+			docType, err = readString(r, e)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if docType != "matroska" && docType != "webm" {
+		return nil, ErrNotMatroska
+	}
+
+	if _, err := r.Seek(header.end(), io.SeekStart); err != nil {
+		return nil, err
+	}
+	segment, err := readElementHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if segment.id != idSegment {
+		return nil, ErrNotMatroska
+	}
+
+	file := &File{Size: size}
+	if segment.size != unknownSize && segment.end() > size {
+		file.Truncated = true
+		segment.size = size - segment.dataOffset
+	}
+
+	p := &parser{r: r, file: file, size: size, segment: segment, scale: 1000000, parsed: make(map[int64]bool)}
+	err = children(r, segment, p.topLevel)
+	if err != nil && !(file.Truncated && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF))) {
+		return nil, err
+	}
+	// elements behind a cluster of unknown size can only be found through
+	// the seek head, which may point at further seek heads
+	for i := 0; i < len(p.seeks); i++ {
+		pos := p.seeks[i]
+		if p.parsed[pos] || pos >= size {
+			continue
+		}
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		e, err := readElementHeader(r)
+		if err != nil {
+			continue
+		}
+		if err := p.topLevel(e); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+	}
+
+	file.Duration = time.Duration(p.duration * float64(p.scale))
+	return file, nil
+}
+
+type parser struct {
+	r        io.ReadSeeker
+	file     *File
+	size     int64
+	segment  element
+	scale    uint64
+	duration float64
+	seeks    []int64
+	parsed   map[int64]bool
+}
+
+func (p *parser) topLevel(e element) error {
+	r, file := p.r, p.file
+	if e.end() > p.size {
+		file.Truncated = true
+		return io.ErrUnexpectedEOF
+	}
+	if p.parsed[e.offset] {
+		return nil
+	}
+	p.parsed[e.offset] = true
+
+	switch e.id {
+	case idSeekHead:
+		return children(r, e, func(e element) error {
+			if e.id != idSeek {
+				return nil
+			}
+			return children(r, e, func(e element) error {
+				if e.id == idSeekPosition {
+					pos, err := readUint(r, e)
+					p.seeks = append(p.seeks, p.segment.dataOffset+int64(pos))
+					return err
+				}
+				return nil
+			})
+		})
+	case idInfo:
+		return children(r, e, func(e element) error {
+			var err error
+			switch e.id {
+			case idTimestampScale:
+				p.scale, err = readUint(r, e)
+			case idDuration:
+				p.duration, err = readFloat(r, e)
+			case idTitle:
+				file.Title, err = readString(r, e)
+			case idMuxingApp:
+				file.MuxingApp, err = readString(r, e)
+			case idWritingApp:
+				file.WritingApp, err = readString(r, e)
+			}
+			return err
+		})
+	case idTracks:
+		return children(r, e, func(e element) error {
+			if e.id != idTrackEntry {
+				return nil
+			}
+			track, err := readTrack(r, e)
+			if err == nil {
+				file.Tracks = append(file.Tracks, track)
+			}
+			return err
+		})
+	case idChapters:
+		return children(r, e, func(e element) error {
+			// only the first edition, that is what players show
+			if e.id != idEditionEntry || file.Chapters != nil {
+				return nil
+			}
+			file.Chapters = []Chapter{}
+			return children(r, e, func(e element) error {
+				if e.id != idChapterAtom {
+					return nil
+				}
+				chapter, err := readChapter(r, e)
+				if err == nil {
+					file.Chapters = append(file.Chapters, chapter)
+				}
+				return err
+			})
+		})
+	case idTags:
+		return children(r, e, func(e element) error {
+			if e.id != idTag {
+				return nil
+			}
+			tag, err := readTag(r, e)
+			if err == nil {
+				file.Tags = append(file.Tags, tag)
+			}
+			return err
+		})
+	case idAttachments:
+		return children(r, e, func(e element) error {
+			if e.id != idAttachedFile {
+				return nil
+			}
+			attachment, err := readAttachment(r, e)
+			if err == nil {
+				file.Attachments = append(file.Attachments, attachment)
+			}
+			return err
+		})
+	}
+	return nil
+}
+
+func readTrack(r io.ReadSeeker, parent element) (Track, error) {
+	track := Track{Enabled: true, Default: true, Language: "eng"}
+	err := children(r, parent, func(e element) error {
+		var err error
+		var v uint64
+		switch e.id {
+		case idTrackNumber:
+			v, err = readUint(r, e)
+			track.Number = int(v)
+		case idTrackUID:
+			track.UID, err = readUint(r, e)
+		case idTrackType:
+			v, err = readUint(r, e)
+			track.Type = TrackType(v)
+		case idFlagEnabled:
+			v, err = readUint(r, e)
+			track.Enabled = v != 0
+		case idFlagDefault:
+			v, err = readUint(r, e)
+			track.Default = v != 0
+		case idFlagForced:
+			v, err = readUint(r, e)
+			track.Forced = v != 0
+		case idFlagVisualImp:
+			v, err = readUint(r, e)
+			track.VisualImpaired = v != 0
+		case idFlagCommentary:
+			v, err = readUint(r, e)
+			track.Commentary = v != 0
+		case idName:
+			track.Name, err = readString(r, e)
+		case idLanguage:
+			track.Language, err = readString(r, e)
+		case idLanguageIETF:
+			track.LanguageIETF, err = readString(r, e)
+		case idCodecID:
+			track.CodecId, err = readString(r, e)
+		case idDefaultDur:
+			v, err = readUint(r, e)
+			track.DefaultDuration = time.Duration(v)
+		case idVideo:
+			err = children(r, e, func(e element) error {
+				var err error
+				var v uint64
+				switch e.id {
+				case idPixelWidth:
+					v, err = readUint(r, e)
+					track.PixelWidth = int(v)
+				case idPixelHeight:
+					v, err = readUint(r, e)
+					track.PixelHeight = int(v)
+				case idDisplayWidth:
+					v, err = readUint(r, e)
+					track.DisplayWidth = int(v)
+				case idDisplayHeight:
+					v, err = readUint(r, e)
+					track.DisplayHeight = int(v)
+				case idFlagInterlaced:
+					// 1 interlaced, 2 progressive
+					v, err = readUint(r, e)
+					track.Interlaced = v == 1
+				}
+				return err
+			})
+		case idAudio:
+			err = children(r, e, func(e element) error {
+				var err error
+				var v uint64
+				switch e.id {
+				case idSamplingFreq:
+					track.SampleRate, err = readFloat(r, e)
+				case idChannels:
+					v, err = readUint(r, e)
+					track.ChannelCount = int(v)
+				case idBitDepth:
+					v, err = readUint(r, e)
+					track.SampleSize = int(v)
+				}
+				return err
+			})
+		}
+		return err
+	})
+	return track, err
+}
+
+func readChapter(r io.ReadSeeker, parent element) (Chapter, error) {
+	var chapter Chapter
+	err := children(r, parent, func(e element) error {
+		var err error
+		var v uint64
+		switch e.id {
+		case idChapterStart:
+			v, err = readUint(r, e)
+			chapter.Start = time.Duration(v)
+		case idChapterEnd:
+			v, err = readUint(r, e)
+			chapter.End = time.Duration(v)
+		case idChapterDisplay:
+			if chapter.Name != "" {
+				return nil
+			}
+			err = children(r, e, func(e element) error {
+				var err error
+				switch e.id {
+				case idChapString:
+					chapter.Name, err = readString(r, e)
+				case idChapLanguage:
+					chapter.Language, err = readString(r, e)
+				}
+				return err
+			})
+		}
+		return err
+	})
+	return chapter, err
+}
+
+func readTag(r io.ReadSeeker, parent element) (Tag, error) {
+	tag := Tag{TargetTypeValue: 50}
+	err := children(r, parent, func(e element) error {
+		switch e.id {
+		case idTargets:
+			return children(r, e, func(e element) error {
+				var err error
+				var v uint64
+				switch e.id {
+				case idTargetTypeVal:
+					v, err = readUint(r, e)
+					tag.TargetTypeValue = int(v)
+				case idTargetType:
+					tag.TargetType, err = readString(r, e)
+				case idTagTrackUID:
+					v, err = readUint(r, e)
+					tag.TrackUIDs = append(tag.TrackUIDs, v)
+				}
+				return err
+			})
+		case idSimpleTag:
+			simple, err := readSimpleTag(r, e)
+			if err == nil {
+				tag.SimpleTags = append(tag.SimpleTags, simple)
+			}
+			return err
+		}
+		return nil
+	})
+	return tag, err
+}
+
+func readSimpleTag(r io.ReadSeeker, parent element) (SimpleTag, error) {
+	simple := SimpleTag{Language: "und"}
+	err := children(r, parent, func(e element) error {
+		var err error
+		switch e.id {
+		case idTagName:
+			simple.Name, err = readString(r, e)
+		case idTagString:
+			simple.Value, err = readString(r, e)
+		case idTagLanguage:
+			simple.Language, err = readString(r, e)
+		case idSimpleTag:
+			var child SimpleTag
+			if child, err = readSimpleTag(r, e); err == nil {
+				simple.Children = append(simple.Children, child)
+			}
+		}
+		return err
+	})
+	return simple, err
+}
+
+func readAttachment(r io.ReadSeeker, parent element) (Attachment, error) {
+	var attachment Attachment
+	err := children(r, parent, func(e element) error {
+		var err error
+		switch e.id {
+		case idFileUID:
+			attachment.UID, err = readUint(r, e)
+		case idFileName:
+			attachment.Name, err = readString(r, e)
+		case idFileDesc:
+			attachment.Description, err = readString(r, e)
+		case idFileMediaType:
+			attachment.MediaType, err = readString(r, e)
+		case idFileData:
+			attachment.Size = e.size
+			attachment.DataOffset = e.dataOffset
+		}
+		return err
+	})
+	return attachment, err
+}
@@ -0,0 +1,187 @@
+package matroska
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func el(id uint32, children ...[]byte) []byte {
+	var data []byte
+	for _, c := range children {
+		data = append(data, c...)
+	}
+	return append(append(encodeElementId(id), encodeSize(uint64(len(data)))...), data...)
+}
+
+func encodeSize(size uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, size)
+	b[0] = 0x01
+	return b
+}
+
+func u(id uint32, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return el(id, b)
+}
+
+func f(id uint32, v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return el(id, b)
+}
+
+func s(id uint32, v string) []byte {
+	return el(id, []byte(v))
+}
+
+func testFile() []byte {
+	header := el(idEBML, s(idDocType, "matroska"))
+	segment := el(idSegment,
+		el(idInfo,
+			u(idTimestampScale, 1000000),
+			f(idDuration, 5551000),
+			s(idTitle, "Movie"),
+			s(idWritingApp, "MakeMKV v1.17.6"),
+		),
+		el(idTracks,
+			el(idTrackEntry,
+				u(idTrackNumber, 1),
+				u(idTrackType, uint64(TrackVideo)),
+				s(idCodecID, "V_MPEGH/ISO/HEVC"),
+				u(idDefaultDur, 41708333),
+				el(idVideo, u(idPixelWidth, 3840), u(idPixelHeight, 2160)),
+			),
+			el(idTrackEntry,
+				u(idTrackNumber, 2),
+				u(idTrackType, uint64(TrackAudio)),
+				s(idCodecID, "A_TRUEHD"),
+				s(idLanguage, "ger"),
+				u(idFlagDefault, 0),
+				u(idFlagCommentary, 1),
+				el(idAudio, f(idSamplingFreq, 48000), u(idChannels, 8)),
+			),
+			el(idTrackEntry,
+				u(idTrackNumber, 3),
+				u(idTrackType, uint64(TrackSubtitle)),
+				s(idCodecID, "S_HDMV/PGS"),
+				u(idFlagForced, 1),
+			),
+		),
+		el(idCluster, make([]byte, 64)),
+		el(idChapters,
+			el(idEditionEntry,
+				el(idChapterAtom, u(idChapterStart, 0), el(idChapterDisplay, s(idChapString, "Chapter 01"))),
+				el(idChapterAtom, u(idChapterStart, uint64(10*time.Minute))),
+			),
+		),
+		el(idTags,
+			el(idTag,
+				el(idTargets, u(idTargetTypeVal, 50)),
+				el(idSimpleTag, s(idTagName, "IMDB"), s(idTagString, "tt0078748")),
+			),
+			el(idTag,
+				el(idTargets, u(idTagTrackUID, 42)),
+				el(idSimpleTag, s(idTagName, "BPS"), s(idTagString, "1234")),
+			),
+		),
+		el(idAttachments,
+			el(idAttachedFile, s(idFileName, "cover.jpg"), s(idFileMediaType, "image/jpeg"), el(idFileData, make([]byte, 16))),
+		),
+	)
+	return append(header, segment...)
+}
+
+func TestRead(t *testing.T) {
+	file, err := Read(bytes.NewReader(testFile()))
+	assert.Nil(t, err)
+	assert.False(t, file.Truncated)
+	assert.Equal(t, "Movie", file.Title)
+	assert.Equal(t, 1*time.Hour+32*time.Minute+31*time.Second, file.Duration)
+	assert.Equal(t, 3, len(file.Tracks))
+	assert.Equal(t, 3840, file.Tracks[0].PixelWidth)
+	assert.Equal(t, "eng", file.Tracks[0].Language)
+	assert.Equal(t, "A_TRUEHD", file.Tracks[1].CodecId)
+	assert.Equal(t, "ger", file.Tracks[1].Language)
+	assert.False(t, file.Tracks[1].Default)
+	assert.Equal(t, 8, file.Tracks[1].ChannelCount)
+	assert.Equal(t, 48000.0, file.Tracks[1].SampleRate)
+	assert.True(t, file.Tracks[2].Forced)
+	assert.Equal(t, 2, len(file.Chapters))
+	assert.Equal(t, "Chapter 01", file.Chapters[0].Name)
+	assert.Equal(t, 10*time.Minute, file.Chapters[1].Start)
+
+	assert.Equal(t, 2, len(file.Tags))
+	assert.Equal(t, []uint64{42}, file.Tags[1].TrackUIDs)
+	imdb, ok := file.Tag("IMDB")
+	assert.True(t, ok)
+	assert.Equal(t, "tt0078748", imdb)
+	_, ok = file.Tag("BPS")
+	assert.False(t, ok)
+
+	assert.Equal(t, 1, len(file.Attachments))
+	assert.Equal(t, "cover.jpg", file.Attachments[0].Name)
+	assert.Equal(t, int64(16), file.Attachments[0].Size)
+}
+
+func TestStreams(t *testing.T) {
+	file, err := Read(bytes.NewReader(testFile()))
+	assert.Nil(t, err)
+
+	video := file.VideoStreams()
+	assert.Equal(t, 1, len(video))
+	assert.Equal(t, "3840x2160", video[0].VideoSize)
+	assert.Equal(t, "16:9", video[0].AspectRatio)
+	assert.Equal(t, "23.976 (24000/1001)", video[0].FrameRate)
+	assert.Equal(t, 3840, video[0].Width)
+	assert.Equal(t, 2160, video[0].Height)
+	assert.Equal(t, "MpegH", video[0].CodecShort)
+
+	audio := file.AudioStreams()
+	assert.Equal(t, 1, len(audio))
+	assert.Equal(t, AudioStreamInfo{Id: 1, LangCode: "ger", CodecId: "A_TRUEHD", CodecShort: "TrueHD", CodecLong: "TrueHD", ChannelCount: 8, SampleRate: 48000, StreamFlags: streamFlagDirectorsComments}, audio[0])
+
+	subtitles := file.SubtitleStreams()
+	assert.Equal(t, 1, len(subtitles))
+	assert.Equal(t, 2, subtitles[0].Id)
+	assert.True(t, subtitles[0].Forced)
+	assert.Equal(t, streamFlagForcedSubtitles, subtitles[0].StreamFlags)
+	assert.Equal(t, "PGS", subtitles[0].CodecShort)
+}
+
+func TestFrameRate(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{41708333, "23.976 (24000/1001)"},
+		{41666667, "24"},
+		{40000000, "25"},
+		{33366667, "29.97 (30000/1001)"},
+		{16683333, "59.94 (60000/1001)"},
+		{20000000, "50"},
+		{0, ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Track{DefaultDuration: test.duration}.FrameRate(), test.duration.String())
+	}
+	num, den := Track{DefaultDuration: 41708333}.FrameRateRatio()
+	assert.Equal(t, []int{24000, 1001}, []int{num, den})
+}
+
+func TestReadTruncated(t *testing.T) {
+	data := testFile()
+	file, err := Read(bytes.NewReader(data[:len(data)-40]))
+	assert.Nil(t, err)
+	assert.True(t, file.Truncated)
+	assert.Equal(t, 3, len(file.Tracks))
+
+	_, err = Read(bytes.NewReader([]byte("not a matroska file")))
+	assert.Equal(t, ErrNotMatroska, err)
+}
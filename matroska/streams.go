@@ -0,0 +1,240 @@
+package matroska
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// The track views below use the field names and value formats of
+// VideoStreamInfo, AudioStreamInfo and SubtitleStreamInfo from the makemkv
+// package, so a scan and the file ripped from it can be compared field by
+// field. Id is the zero based track index, which is what makemkvcon uses as
+// stream id.
+
+type VideoStreamInfo struct {
+	Id          int
+	Name        string
+	LangCode    string
+	CodecId     string
+	CodecShort  string
+	CodecLong   string
+	VideoSize   string
+	AspectRatio string
+	FrameRate   string
+	Width       int
+	Height      int
+	Interlaced  bool
+	StreamFlags int
+	Default     bool
+	Forced      bool
+}
+
+type AudioStreamInfo struct {
+	Id           int
+	Name         string
+	LangCode     string
+	CodecId      string
+	CodecShort   string
+	CodecLong    string
+	ChannelCount int
+	SampleRate   int
+	SampleSize   int
+	StreamFlags  int
+	Default      bool
+	Forced       bool
+}
+
+type SubtitleStreamInfo struct {
+	Id          int
+	Name        string
+	LangCode    string
+	CodecId     string
+	CodecShort  string
+	CodecLong   string
+	StreamFlags int
+	Default     bool
+	Forced      bool
+}
+
+// the AP_AVStreamFlag values of apdefs.h that a track can carry
+const (
+	streamFlagDirectorsComments   = 1
+	streamFlagForVisuallyImpaired = 4
+	streamFlagForcedSubtitles     = 4096
+)
+
+// makemkvcon's ap_iaCodecShort and ap_iaCodecLong for the codec ids it
+// writes. The long name of a video stream also has the profile, which the
+// file does not tell, and A_DTS is named by its core since DTS-HD MA has the
+// same id.
+var codecNames = map[string][2]string{
+	"V_MPEG2":          {"Mpeg2", "Mpeg2"},
+	"V_MPEG4/ISO/AVC":  {"Mpeg4", "Mpeg4 AVC"},
+	"V_MPEG4/ISO/MVC":  {"Mpeg4 MVC", "Mpeg4 MVC"},
+	"V_MPEGH/ISO/HEVC": {"MpegH", "MpegH HEVC"},
+	"V_MS/VFW/WVC1":    {"VC-1", "VC-1"},
+	"A_AC3":            {"DD", "Dolby Digital"},
+	"A_EAC3":           {"DD+", "Dolby Digital Plus"},
+	"A_DTS":            {"DTS", "DTS"},
+	"A_TRUEHD":         {"TrueHD", "TrueHD"},
+	"A_PCM/INT/LIT":    {"LPCM", "LPCM"},
+	"A_PCM/INT/BIG":    {"LPCM", "LPCM"},
+	"A_FLAC":           {"FLAC", "FLAC"},
+	"S_HDMV/PGS":       {"PGS", "HDMV PGS Subtitles"},
+	"S_VOBSUB":         {"VobSub", "Dvd Subtitles"},
+}
+
+func (f *File) VideoStreams() []VideoStreamInfo {
+	var streams []VideoStreamInfo
+	for _, t := range f.Tracks {
+		if t.Type != TrackVideo {
+			continue
+		}
+		names := codecNames[t.CodecId]
+		streams = append(streams, VideoStreamInfo{
+			Id:          t.Number - 1,
+			Name:        t.Name,
+			LangCode:    t.Language,
+			CodecId:     t.CodecId,
+			CodecShort:  names[0],
+			CodecLong:   names[1],
+			VideoSize:   strconv.Itoa(t.PixelWidth) + "x" + strconv.Itoa(t.PixelHeight),
+			AspectRatio: t.AspectRatio(),
+			FrameRate:   t.FrameRate(),
+			Width:       t.PixelWidth,
+			Height:      t.PixelHeight,
+			Interlaced:  t.Interlaced,
+			StreamFlags: t.streamFlags(),
+			Default:     t.Default,
+			Forced:      t.Forced,
+		})
+	}
+	return streams
+}
+
+func (f *File) AudioStreams() []AudioStreamInfo {
+	var streams []AudioStreamInfo
+	for _, t := range f.Tracks {
+		if t.Type != TrackAudio {
+			continue
+		}
+		names := codecNames[t.CodecId]
+		streams = append(streams, AudioStreamInfo{
+			Id:           t.Number - 1,
+			Name:         t.Name,
+			LangCode:     t.Language,
+			CodecId:      t.CodecId,
+			CodecShort:   names[0],
+			CodecLong:    names[1],
+			ChannelCount: t.ChannelCount,
+			SampleRate:   int(t.SampleRate),
+			SampleSize:   t.SampleSize,
+			StreamFlags:  t.streamFlags(),
+			Default:      t.Default,
+			Forced:       t.Forced,
+		})
+	}
+	return streams
+}
+
+func (f *File) SubtitleStreams() []SubtitleStreamInfo {
+	var streams []SubtitleStreamInfo
+	for _, t := range f.Tracks {
+		if t.Type != TrackSubtitle {
+			continue
+		}
+		names := codecNames[t.CodecId]
+		streams = append(streams, SubtitleStreamInfo{
+			Id:          t.Number - 1,
+			Name:        t.Name,
+			LangCode:    t.Language,
+			CodecId:     t.CodecId,
+			CodecShort:  names[0],
+			CodecLong:   names[1],
+			StreamFlags: t.streamFlags(),
+			Default:     t.Default,
+			Forced:      t.Forced,
+		})
+	}
+	return streams
+}
+
+func (t Track) streamFlags() int {
+	flags := 0
+	if t.Commentary {
+		flags |= streamFlagDirectorsComments
+	}
+	if t.VisualImpaired {
+		flags |= streamFlagForVisuallyImpaired
+	}
+	if t.Forced && t.Type == TrackSubtitle {
+		flags |= streamFlagForcedSubtitles
+	}
+	return flags
+}
+
+// AspectRatio reduces the display size to a ratio like "16:9".
+func (t Track) AspectRatio() string {
+	w, h := t.DisplayWidth, t.DisplayHeight
+	if w == 0 || h == 0 {
+		w, h = t.PixelWidth, t.PixelHeight
+	}
+	if w == 0 || h == 0 {
+		return ""
+	}
+	d := gcd(w, h)
+	return strconv.Itoa(w/d) + ":" + strconv.Itoa(h/d)
+}
+
+// FrameRateRatio derives the frame rate from the default frame duration,
+// which is rounded to the nanosecond, so NTSC rates come out as their exact
+// x/1001 ratio.
+func (t Track) FrameRateRatio() (num int, den int) {
+	if t.DefaultDuration <= 0 {
+		return 0, 0
+	}
+	fps := float64(time.Second) / float64(t.DefaultDuration)
+	if ntsc := math.Round(fps * 1.001); math.Abs(fps*1.001-ntsc) < 0.001 && math.Abs(fps-ntsc) > 0.001 {
+		return int(ntsc) * 1000, 1001
+	}
+	num, den = int(math.Round(fps*1000)), 1000
+	d := gcd(num, den)
+	return num / d, den / d
+}
+
+// FrameRate formats the frame rate like makemkvcon, "25" or
+// "23.976 (24000/1001)".
+func (t Track) FrameRate() string {
+	num, den := t.FrameRateRatio()
+	if den == 0 {
+		return ""
+	}
+	fps := strconv.FormatFloat(math.Round(float64(num)/float64(den)*1000)/1000, 'f', -1, 64)
+	if den == 1 {
+		return fps
+	}
+	return fps + " (" + strconv.Itoa(num) + "/" + strconv.Itoa(den) + ")"
+}
+
+// Tag returns the value of the first global tag with the given name.
+func (f *File) Tag(name string) (string, bool) {
+	for _, tag := range f.Tags {
+		if len(tag.TrackUIDs) > 0 {
+			continue
+		}
+		for _, simple := range tag.SimpleTags {
+			if simple.Name == name {
+				return simple.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
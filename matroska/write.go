@@ -0,0 +1,610 @@
+package matroska
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+const idCRC32 = 0xBF
+
+var ErrNotEditable = errors.New("matroska: file layout does not allow editing in place")
+
+// masters we descend into when editing, everything else is kept as raw bytes
+var editableMasters = map[uint32]bool{
+	idSeekHead:   true,
+	idSeek:       true,
+	idInfo:       true,
+	idTracks:     true,
+	idTrackEntry: true,
+	idTags:       true,
+	idTag:        true,
+	idTargets:    true,
+	idSimpleTag:  true,
+}
+
+type Edits struct {
+	Title *string
+	// keyed by track number
+	Tracks map[int]TrackEdits
+	// merged into the existing tags, simple tags with the same name and
+	// targets are replaced
+	Tags []Tag
+}
+
+type TrackEdits struct {
+	Name         *string
+	Language     *string
+	LanguageIETF *string
+	Default      *bool
+	Forced       *bool
+}
+
+// Edit changes header values of the file at path without touching the
+// clusters. Elements are rewritten in place when they fit in their old
+// space plus any Void padding after them, otherwise they are moved to the
+// end of the file and the seek head is updated. A seek head too small to
+// point at a second one makes Edit fail with ErrNotEditable before anything
+// is moved.
+func Edit(path string, edits Edits) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	l, err := readLayout(f)
+	if err != nil {
+		return err
+	}
+
+	if edits.Title != nil {
+		info, err := l.load(f, idInfo)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			return ErrNotEditable
+		}
+		info.set(idTitle, []byte(*edits.Title))
+		if err := l.store(f, info); err != nil {
+			return err
+		}
+	}
+
+	if len(edits.Tracks) > 0 {
+		tracks, err := l.load(f, idTracks)
+		if err != nil {
+			return err
+		}
+		if tracks == nil {
+			return ErrNotEditable
+		}
+		for _, entry := range tracks.all(idTrackEntry) {
+			number := entry.uint(idTrackNumber)
+			if te, ok := edits.Tracks[int(number)]; ok {
+				te.apply(entry)
+			}
+		}
+		if err := l.store(f, tracks); err != nil {
+			return err
+		}
+	}
+
+	if len(edits.Tags) > 0 {
+		tags, err := l.load(f, idTags)
+		if err != nil {
+			return err
+		}
+		if tags == nil {
+			tags = &node{id: idTags, master: true}
+		}
+		for _, tag := range edits.Tags {
+			mergeTag(tags, tag)
+		}
+		if err := l.store(f, tags); err != nil {
+			return err
+		}
+	}
+
+	return l.updateSeekHead(f)
+}
+
+func (te TrackEdits) apply(entry *node) {
+	if te.Name != nil {
+		entry.set(idName, []byte(*te.Name))
+	}
+	if te.Language != nil {
+		entry.set(idLanguage, []byte(*te.Language))
+	}
+	if te.LanguageIETF != nil {
+		entry.set(idLanguageIETF, []byte(*te.LanguageIETF))
+	}
+	if te.Default != nil {
+		entry.set(idFlagDefault, encodeBool(*te.Default))
+	}
+	if te.Forced != nil {
+		entry.set(idFlagForced, encodeBool(*te.Forced))
+	}
+}
+
+func mergeTag(tags *node, tag Tag) {
+	var target *node
+	for _, existing := range tags.all(idTag) {
+		if sameTargets(existing, tag) {
+			target = existing
+			break
+		}
+	}
+	if target == nil {
+		targets := &node{id: idTargets, master: true}
+		targets.set(idTargetTypeVal, encodeUint(uint64(tag.TargetTypeValue)))
+		if tag.TargetType != "" {
+			targets.set(idTargetType, []byte(tag.TargetType))
+		}
+		for _, uid := range tag.TrackUIDs {
+			targets.children = append(targets.children, &node{id: idTagTrackUID, data: encodeUint(uid)})
+		}
+		target = &node{id: idTag, master: true, children: []*node{targets}}
+		tags.children = append(tags.children, target)
+	}
+
+	for _, simple := range tag.SimpleTags {
+		replaced := false
+		for i, existing := range target.children {
+			if existing.id == idSimpleTag && existing.string(idTagName) == simple.Name {
+				target.children[i] = simpleTagNode(simple)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			target.children = append(target.children, simpleTagNode(simple))
+		}
+	}
+}
+
+func sameTargets(n *node, tag Tag) bool {
+	targets := n.child(idTargets)
+	value := uint64(50)
+	var uids []uint64
+	if targets != nil {
+		if v := targets.child(idTargetTypeVal); v != nil {
+			value = v.uint(0)
+		}
+		for _, uid := range targets.all(idTagTrackUID) {
+			uids = append(uids, uid.uint(0))
+		}
+	}
+	if value != uint64(tag.TargetTypeValue) || len(uids) != len(tag.TrackUIDs) {
+		return false
+	}
+	for i := range uids {
+		if uids[i] != tag.TrackUIDs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func simpleTagNode(simple SimpleTag) *node {
+	n := &node{id: idSimpleTag, master: true}
+	n.set(idTagName, []byte(simple.Name))
+	if simple.Language != "" {
+		n.set(idTagLanguage, []byte(simple.Language))
+	}
+	n.set(idTagString, []byte(simple.Value))
+	for _, child := range simple.Children {
+		n.children = append(n.children, simpleTagNode(child))
+	}
+	return n
+}
+
+//////////////////////////// tree ////////////////////////////
+
+type node struct {
+	id       uint32
+	master   bool
+	data     []byte
+	children []*node
+}
+
+func readNode(r io.ReadSeeker, e element) (*node, error) {
+	n := &node{id: e.id, master: editableMasters[e.id]}
+	if !n.master {
+		data, err := readRaw(r, e)
+		n.data = data
+		return n, err
+	}
+	err := children(r, e, func(e element) error {
+		// checksums would be wrong once we change anything
+		if e.id == idCRC32 {
+			return nil
+		}
+		child, err := readNode(r, e)
+		if err == nil {
+			n.children = append(n.children, child)
+		}
+		return err
+	})
+	return n, err
+}
+
+func readRaw(r io.ReadSeeker, e element) ([]byte, error) {
+	if e.size < 0 {
+		return nil, ErrNotEditable
+	}
+	if _, err := r.Seek(e.dataOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, e.size)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func (n *node) child(id uint32) *node {
+	for _, c := range n.children {
+		if c.id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) all(id uint32) []*node {
+	var nodes []*node
+	for _, c := range n.children {
+		if c.id == id {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+// uint returns the value of the child with the given id, or of n itself
+// when id is 0.
+func (n *node) uint(id uint32) uint64 {
+	c := n
+	if id != 0 {
+		if c = n.child(id); c == nil {
+			return 0
+		}
+	}
+	var v uint64
+	for _, b := range c.data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func (n *node) string(id uint32) string {
+	if c := n.child(id); c != nil {
+		return string(c.data)
+	}
+	return ""
+}
+
+func (n *node) set(id uint32, data []byte) {
+	if c := n.child(id); c != nil {
+		c.data = data
+		return
+	}
+	n.children = append(n.children, &node{id: id, data: data})
+}
+
+func (n *node) payload() []byte {
+	if !n.master {
+		return n.data
+	}
+	var data []byte
+	for _, c := range n.children {
+		data = append(data, c.encode(0)...)
+	}
+	return data
+}
+
+// encode writes the element, using sizeLen bytes for the size field or the
+// shortest possible encoding when sizeLen is 0.
+func (n *node) encode(sizeLen int) []byte {
+	data := n.payload()
+	if sizeLen == 0 {
+		sizeLen = vintLength(uint64(len(data)))
+	}
+	out := append(encodeElementId(n.id), encodeVint(uint64(len(data)), sizeLen)...)
+	return append(out, data...)
+}
+
+func encodeElementId(id uint32) []byte {
+	switch {
+	case id >= 1<<24:
+		return []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	case id >= 1<<16:
+		return []byte{byte(id >> 16), byte(id >> 8), byte(id)}
+	case id >= 1<<8:
+		return []byte{byte(id >> 8), byte(id)}
+	default:
+		return []byte{byte(id)}
+	}
+}
+
+func vintLength(v uint64) int {
+	length := 1
+	for length < 8 && v >= uint64(1)<<(7*length)-1 {
+		length++
+	}
+	return length
+}
+
+func encodeVint(v uint64, length int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v|uint64(1)<<(7*length))
+	return b[8-length:]
+}
+
+func encodeUint(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// void returns a Void element exactly size bytes long, size must be >= 2.
+func void(size int64) []byte {
+	sizeLen := 1
+	if size-2 >= 127 {
+		sizeLen = 8
+	}
+	data := size - 1 - int64(sizeLen)
+	out := append([]byte{idVoid}, encodeVint(uint64(data), sizeLen)...)
+	return append(out, make([]byte, data)...)
+}
+
+/////////////////////////// layout ///////////////////////////
+
+type layout struct {
+	segment  element
+	elements []element
+	// top level elements that were moved or added, by id
+	moved map[uint32]int64
+}
+
+func readLayout(f *os.File) (*layout, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	header, err := readElementHeader(f)
+	if err != nil || header.id != idEBML {
+		return nil, ErrNotMatroska
+	}
+	if _, err := f.Seek(header.end(), io.SeekStart); err != nil {
+		return nil, err
+	}
+	segment, err := readElementHeader(f)
+	if err != nil || segment.id != idSegment {
+		return nil, ErrNotMatroska
+	}
+	// we can only grow the file if the segment is its last element
+	if segment.size != unknownSize && segment.end() != size {
+		return nil, ErrNotEditable
+	}
+
+	l := &layout{segment: segment, moved: make(map[uint32]int64)}
+	err = children(f, segment, func(e element) error {
+		l.elements = append(l.elements, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// children stops at an element of unknown size, what follows it could
+	// not be found again after moving anything behind it
+	if len(l.elements) == 0 || l.elements[len(l.elements)-1].end() != size {
+		return nil, ErrNotEditable
+	}
+	return l, nil
+}
+
+func (l *layout) find(id uint32) (int, bool) {
+	for i, e := range l.elements {
+		if e.id == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (l *layout) load(f *os.File, id uint32) (*node, error) {
+	i, ok := l.find(id)
+	if !ok {
+		return nil, nil
+	}
+	return readNode(f, l.elements[i])
+}
+
+// room is the space of element i and the Void elements that follow it.
+func (l *layout) room(i int) (start int64, end int64) {
+	start, end = l.elements[i].offset, l.elements[i].end()
+	for j := i + 1; j < len(l.elements) && l.elements[j].id == idVoid; j++ {
+		end = l.elements[j].end()
+	}
+	return start, end
+}
+
+// writeAt writes n at start, filling the room up to end with a Void. It
+// returns false without writing if n does not fit.
+func writeAt(f *os.File, n *node, start int64, end int64) (bool, error) {
+	encoded := n.encode(0)
+	rest := end - start - int64(len(encoded))
+	switch {
+	case rest < 0:
+		return false, nil
+	case rest == 1:
+		// a Void needs two bytes, take the extra byte in the size field
+		sizeLen := vintLength(uint64(len(n.payload()))) + 1
+		if sizeLen > 8 {
+			return false, nil
+		}
+		encoded = n.encode(sizeLen)
+	case rest > 1:
+		encoded = append(encoded, void(rest)...)
+	}
+	_, err := f.WriteAt(encoded, start)
+	return true, err
+}
+
+// store writes n over its old element, using the Void elements that follow
+// it as extra room, or moves it to the end of the segment.
+func (l *layout) store(f *os.File, n *node) error {
+	i, found := l.find(n.id)
+	if found {
+		start, end := l.room(i)
+		ok, err := writeAt(f, n, start, end)
+		if err != nil || ok {
+			return err
+		}
+	}
+
+	// a moved element is only found through the seek head, make sure it
+	// can be pointed at before anything is moved
+	for j, e := range l.elements {
+		if e.id != idSeekHead {
+			continue
+		}
+		start, end := l.room(j)
+		if end-start < int64(len(seekPointer(math.MaxInt64).encode(0))) {
+			return ErrNotEditable
+		}
+	}
+
+	if found {
+		start := l.elements[i].offset
+		if _, err := f.WriteAt(void(l.elements[i].end()-start), start); err != nil {
+			return err
+		}
+		l.elements[i].id = idVoid
+	}
+	position, err := l.append(f, n.encode(0))
+	if err != nil {
+		return err
+	}
+	l.moved[n.id] = position
+	return nil
+}
+
+// append writes encoded at the end of the segment and returns its offset.
+func (l *layout) append(f *os.File, encoded []byte) (int64, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt(encoded, end); err != nil {
+		return 0, err
+	}
+
+	if l.segment.size == unknownSize {
+		return end, nil
+	}
+	l.segment.size += int64(len(encoded))
+	sizeLen := int(l.segment.dataOffset - l.segment.offset - 4)
+	if vintLength(uint64(l.segment.size)) > sizeLen {
+		return 0, ErrNotEditable
+	}
+	_, err = f.WriteAt(encodeVint(uint64(l.segment.size), sizeLen), l.segment.offset+4)
+	return end, err
+}
+
+// seekPointer is a seek head with a single entry for another seek head.
+func seekPointer(position int64) *node {
+	seek := &node{id: idSeek, master: true}
+	seek.set(idSeekID, encodeElementId(idSeekHead))
+	seek.set(idSeekPosition, encodeUint(uint64(position)))
+	return &node{id: idSeekHead, master: true, children: []*node{seek}}
+}
+
+// updateSeekHead points the seek entries of moved elements at their new
+// position. Entries are added to the last seek head, which is the complete
+// one when the first only points at a second one. A seek head that outgrows
+// its room is moved to the end and replaced by a pointer to it.
+func (l *layout) updateSeekHead(f *os.File) error {
+	if len(l.moved) == 0 {
+		return nil
+	}
+	var heads []int
+	for i, e := range l.elements {
+		if e.id == idSeekHead {
+			heads = append(heads, i)
+		}
+	}
+	if len(heads) == 0 {
+		// without a seek head readers have to scan, which finds the
+		// moved elements as well
+		return nil
+	}
+
+	ids := make([]uint32, 0, len(l.moved))
+	for id := range l.moved {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+
+	for k, i := range heads {
+		seekHead, err := readNode(f, l.elements[i])
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			position := encodeUint(uint64(l.moved[id] - l.segment.dataOffset))
+			var entry *node
+			for _, seek := range seekHead.all(idSeek) {
+				if seekId := seek.child(idSeekID); seekId != nil && string(seekId.data) == string(encodeElementId(id)) {
+					entry = seek
+					break
+				}
+			}
+			if entry == nil {
+				if k < len(heads)-1 {
+					continue
+				}
+				entry = &node{id: idSeek, master: true}
+				entry.set(idSeekID, encodeElementId(id))
+				seekHead.children = append(seekHead.children, entry)
+			}
+			entry.set(idSeekPosition, position)
+		}
+
+		start, end := l.room(i)
+		ok, err := writeAt(f, seekHead, start, end)
+		if err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+		position, err := l.append(f, seekHead.encode(0))
+		if err != nil {
+			return err
+		}
+		ok, err = writeAt(f, seekPointer(position-l.segment.dataOffset), start, end)
+		if err != nil {
+			return err
+		} else if !ok {
+			return ErrNotEditable
+		}
+	}
+	return nil
+}
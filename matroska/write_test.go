@@ -0,0 +1,219 @@
+package matroska
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFile writes a file whose seek head points at the tracks and has
+// seekPadding bytes of Void after it.
+func writeTestFile(t *testing.T, seekPadding int, padding int) string {
+	header := el(idEBML, s(idDocType, "matroska"))
+	info := el(idInfo, u(idTimestampScale, 1000000), f(idDuration, 1000), s(idTitle, "Old"))
+	var voids []byte
+	if padding > 0 {
+		voids = void(int64(padding))
+	}
+	// a compact seek head of 20 bytes with a two byte position
+	tracksPosition := 20 + seekPadding + len(info) + len(voids)
+	seek := &node{id: idSeek, master: true}
+	seek.set(idSeekID, encodeElementId(idTracks))
+	seek.set(idSeekPosition, []byte{byte(tracksPosition >> 8), byte(tracksPosition)})
+	seekHead := (&node{id: idSeekHead, master: true, children: []*node{seek}}).encode(0)
+	if seekPadding > 0 {
+		seekHead = append(seekHead, void(int64(seekPadding))...)
+	}
+	segment := el(idSegment,
+		seekHead,
+		info,
+		voids,
+		el(idTracks,
+			el(idTrackEntry,
+				u(idTrackNumber, 1),
+				u(idTrackUID, 101),
+				u(idTrackType, uint64(TrackAudio)),
+				s(idCodecID, "A_AC3"),
+				el(0x63A2, []byte{1, 2, 3, 4}),
+			),
+			el(idTrackEntry,
+				u(idTrackNumber, 2),
+				u(idTrackUID, 102),
+				u(idTrackType, uint64(TrackSubtitle)),
+				s(idCodecID, "S_HDMV/PGS"),
+			),
+		),
+		el(idCluster, make([]byte, 128)),
+	)
+	path := filepath.Join(t.TempDir(), "test.mkv")
+	assert.Nil(t, os.WriteFile(path, append(header, segment...), 0644))
+	return path
+}
+
+// seekTargets follows the seek heads like a reader that does not scan the
+// file, checking that every entry points at an element with its id.
+func seekTargets(t *testing.T, path string) map[uint32]int64 {
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+	l, err := readLayout(f)
+	assert.Nil(t, err)
+
+	targets := make(map[uint32]int64)
+	var follow func(e element)
+	follow = func(e element) {
+		seekHead, err := readNode(f, e)
+		assert.Nil(t, err)
+		for _, seek := range seekHead.all(idSeek) {
+			id := uint32((&node{data: seek.child(idSeekID).data}).uint(0))
+			position := l.segment.dataOffset + int64(seek.uint(idSeekPosition))
+			_, err := f.Seek(position, io.SeekStart)
+			assert.Nil(t, err)
+			target, err := readElementHeader(f)
+			assert.Nil(t, err)
+			assert.Equal(t, id, target.id, "seek entry %x", id)
+			if id == idSeekHead {
+				follow(target)
+			} else {
+				targets[id] = position
+			}
+		}
+	}
+	assert.Equal(t, uint32(idSeekHead), l.elements[0].id)
+	follow(l.elements[0])
+	return targets
+}
+
+func TestEdit(t *testing.T) {
+	for _, padding := range []int{0, 3, 256} {
+		path := writeTestFile(t, 64, padding)
+		title := "A much longer title than before"
+		name := "Surround 5.1"
+		lang := "ger"
+		yes := true
+
+		err := Edit(path, Edits{
+			Title: &title,
+			Tracks: map[int]TrackEdits{
+				1: {Name: &name, Language: &lang},
+				2: {Forced: &yes, Default: &yes},
+			},
+			Tags: []Tag{
+				{TargetTypeValue: 50, SimpleTags: []SimpleTag{{Name: "IMDB", Value: "tt0078748"}}},
+			},
+		})
+		assert.Nil(t, err)
+
+		file, err := Open(path)
+		assert.Nil(t, err)
+		assert.False(t, file.Truncated)
+		assert.Equal(t, title, file.Title)
+		assert.Equal(t, 2, len(file.Tracks))
+		assert.Equal(t, name, file.Tracks[0].Name)
+		assert.Equal(t, "ger", file.Tracks[0].Language)
+		assert.Equal(t, "A_AC3", file.Tracks[0].CodecId)
+		assert.True(t, file.Tracks[1].Forced)
+		value, ok := file.Tag("IMDB")
+		assert.True(t, ok)
+		assert.Equal(t, "tt0078748", value)
+		targets := seekTargets(t, path)
+		assert.Contains(t, targets, uint32(idTracks))
+		assert.Contains(t, targets, uint32(idTags))
+
+		// replacing a tag keeps a single value
+		err = Edit(path, Edits{Tags: []Tag{
+			{TargetTypeValue: 50, SimpleTags: []SimpleTag{{Name: "IMDB", Value: "tt0090605"}}},
+		}})
+		assert.Nil(t, err)
+		file, err = Open(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(file.Tags))
+		value, _ = file.Tag("IMDB")
+		assert.Equal(t, "tt0090605", value)
+		assert.Contains(t, seekTargets(t, path), uint32(idTags))
+	}
+}
+
+func TestEditMovesSeekHead(t *testing.T) {
+	path := writeTestFile(t, 12, 0)
+	name := strings.Repeat("A much longer name than before. ", 4)
+	err := Edit(path, Edits{
+		Tracks: map[int]TrackEdits{1: {Name: &name}},
+		Tags:   []Tag{{TargetTypeValue: 50, SimpleTags: []SimpleTag{{Name: "IMDB", Value: "tt0078748"}}}},
+	})
+	assert.Nil(t, err)
+
+	// the seek head in front only points at the moved one
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	l, err := readLayout(f)
+	assert.Nil(t, err)
+	front, err := readNode(f, l.elements[0])
+	f.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(front.all(idSeek)))
+	assert.Equal(t, encodeElementId(idSeekHead), front.child(idSeek).child(idSeekID).data)
+	targets := seekTargets(t, path)
+	assert.Equal(t, 2, len(targets))
+
+	file, err := Open(path)
+	assert.Nil(t, err)
+	assert.Equal(t, name, file.Tracks[0].Name)
+	value, _ := file.Tag("IMDB")
+	assert.Equal(t, "tt0078748", value)
+
+	// moving the tags again updates the moved seek head
+	err = Edit(path, Edits{Tags: []Tag{
+		{TargetTypeValue: 50, SimpleTags: []SimpleTag{{Name: "IMDB", Value: "tt0078748"}, {Name: "TMDB", Value: "movie/348"}}},
+	}})
+	assert.Nil(t, err)
+	file, err = Open(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "Old", file.Title)
+	value, _ = file.Tag("TMDB")
+	assert.Equal(t, "movie/348", value)
+	assert.Equal(t, 2, len(file.Tracks))
+	moved := seekTargets(t, path)
+	assert.Equal(t, targets[idTracks], moved[idTracks])
+	assert.NotEqual(t, targets[idTags], moved[idTags])
+}
+
+func TestEditSeekHeadTooSmall(t *testing.T) {
+	path := writeTestFile(t, 0, 0)
+	before, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	title := strings.Repeat("A much longer title than before. ", 4)
+	assert.Equal(t, ErrNotEditable, Edit(path, Edits{Title: &title}))
+	after, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, before, after)
+
+	// what fits in place is still written
+	title = "New"
+	assert.Nil(t, Edit(path, Edits{Title: &title}))
+	file, err := Open(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "New", file.Title)
+}
+
+func TestEditLiveCluster(t *testing.T) {
+	header := el(idEBML, s(idDocType, "matroska"))
+	cluster := append(encodeElementId(idCluster), 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	segment := el(idSegment, el(idInfo, s(idTitle, "Old")), cluster, make([]byte, 64))
+	path := filepath.Join(t.TempDir(), "live.mkv")
+	assert.Nil(t, os.WriteFile(path, append(header, segment...), 0644))
+
+	title := "New"
+	assert.Equal(t, ErrNotEditable, Edit(path, Edits{Title: &title}))
+}
+
+func TestVoid(t *testing.T) {
+	for _, size := range []int64{2, 3, 128, 129, 130, 1000} {
+		assert.Equal(t, int(size), len(void(size)))
+	}
+}
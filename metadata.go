@@ -0,0 +1,223 @@
+package makemkv
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type MediaKind string
+
+const (
+	MediaMovie  MediaKind = "movie"
+	MediaSeries MediaKind = "series"
+)
+
+// DiscQuery is what a MetadataProvider gets to identify a disc.
+type DiscQuery struct {
+	Name       string
+	VolumeName string
+	// Name or VolumeName cleaned up for searching, without disc numbers,
+	// underscores and years
+	Title string
+	Year  int
+	// parsed from labels like "SHOW_S2_D1", 0 if unknown
+	Season int
+	Disc   int
+	// length of the main feature
+	Runtime time.Duration
+	// lengths of the titles that look like episodes, in title order
+	EpisodeRuntimes []time.Duration
+	// the Fingerprint of the disc, for providers that remember discs
+	Fingerprint string
+}
+
+type Episode struct {
+	Season  int
+	Number  int
+	Title   string
+	Runtime time.Duration
+}
+
+type MetadataMatch struct {
+	Kind    MediaKind
+	Title   string
+	Year    int
+	Runtime time.Duration
+	ImdbId  string
+	TmdbId  string
+	// for series, the season the disc belongs to and its episodes
+	Season   int
+	Episodes []Episode
+	// how well the match fits the query, from 0 to 1
+	Score float64
+}
+
+// MetadataProvider resolves a disc to the movie or season on it. Lookup
+// returns the candidates best match first.
+type MetadataProvider interface {
+	Lookup(ctx context.Context, query DiscQuery) ([]MetadataMatch, error)
+}
+
+var (
+	// the single letter forms need a separator before and the number right
+	// after them, so "RED_2" and "CARS_2" keep their last letter
+	discLabelRegex   = regexp.MustCompile(`(?i)(?:[\s_.-]*(?:disc|disk|dvd|bd)[\s_.-]*|[\s_.-]+d)(\d{1,2})$`)
+	seasonLabelRegex = regexp.MustCompile(`(?i)(?:[\s_.-]*(?:season|series)[\s_.-]*|[\s_.-]+s)(\d{1,2})$`)
+	yearLabelRegex   = regexp.MustCompile(`[\s_.(-]*((?:19|20)\d\d)\)?$`)
+)
+
+// NewDiscQuery builds the query for a scanned disc.
+func NewDiscQuery(info DiscInfo) DiscQuery {
+	q := DiscQuery{Name: info.Name, VolumeName: info.VolumeName, Fingerprint: Fingerprint(info)}
+	if main, ok := MainFeature(info); ok {
+		q.Runtime = main.Duration
+	}
+	for _, t := range Episodes(info) {
+		q.EpisodeRuntimes = append(q.EpisodeRuntimes, t.Duration)
+	}
+
+	name := info.Name
+	if name == "" {
+		name = info.VolumeName
+	}
+	q.Title, q.Season, q.Disc, q.Year = parseDiscLabel(name)
+	// the volume label has the disc and season numbers more often than the
+	// name makemkvcon shows
+	if q.Season == 0 || q.Disc == 0 {
+		_, season, disc, _ := parseDiscLabel(info.VolumeName)
+		if q.Season == 0 {
+			q.Season = season
+		}
+		if q.Disc == 0 {
+			q.Disc = disc
+		}
+	}
+	return q
+}
+
+// parseDiscLabel splits labels like "THE_SHOW_S2_D1" or "Movie (1999)".
+func parseDiscLabel(label string) (title string, season int, disc int, year int) {
+	title = strings.TrimSpace(label)
+	for {
+		if m := discLabelRegex.FindStringSubmatchIndex(title); m != nil && disc == 0 && m[0] > 0 {
+			disc, _ = strconv.Atoi(title[m[2]:m[3]])
+			title = title[:m[0]]
+		} else if m := seasonLabelRegex.FindStringSubmatchIndex(title); m != nil && season == 0 && m[0] > 0 {
+			season, _ = strconv.Atoi(title[m[2]:m[3]])
+			title = title[:m[0]]
+		} else if m := yearLabelRegex.FindStringSubmatchIndex(title); m != nil && year == 0 && m[0] > 0 && releaseYear(title[m[2]:m[3]]) {
+			year, _ = strconv.Atoi(title[m[2]:m[3]])
+			title = title[:m[0]]
+		} else {
+			break
+		}
+	}
+
+	title = strings.Join(strings.FieldsFunc(title, func(r rune) bool {
+		return r == '_' || r == '.' || r == ' '
+	}), " ")
+	if title == strings.ToUpper(title) {
+		words := strings.Fields(strings.ToLower(title))
+		for i, w := range words {
+			r := []rune(w)
+			words[i] = string(unicode.ToUpper(r[0])) + string(r[1:])
+		}
+		title = strings.Join(words, " ")
+	}
+	return title, season, disc, year
+}
+
+// releaseYear keeps titles like "Blade Runner 2049" intact.
+func releaseYear(s string) bool {
+	year, _ := strconv.Atoi(s)
+	return year <= time.Now().Year()+1
+}
+
+// Metadata returns the metadata of a movie match for the naming templates
+// and WriteTags.
+func (m MetadataMatch) Metadata() Metadata {
+	return Metadata{Title: m.Title, Year: m.Year, ImdbId: m.ImdbId, TmdbId: m.TmdbId}
+}
+
+// MetadataFunc returns the metadata of every title of info. For a series the
+// episodes of the disc are matched to the episodes of the season by their
+// runtimes, other titles get the metadata of the show.
+func (m MetadataMatch) MetadataFunc(info DiscInfo) MetadataFunc {
+	show := Metadata{Title: m.Title, Year: m.Year, Show: m.Title, Season: m.Season, ImdbId: m.ImdbId, TmdbId: m.TmdbId}
+	if m.Kind != MediaSeries {
+		return StaticMetadata(m.Metadata())
+	}
+
+	titles := Episodes(info)
+	hint := 0
+	if disc := NewDiscQuery(info).Disc; disc > 0 {
+		hint = (disc - 1) * len(titles)
+	}
+	episodes := AssignEpisodes(titles, m.Episodes, hint)
+	return func(title TitleInfo) Metadata {
+		e, ok := episodes[title.Id]
+		if !ok {
+			return show
+		}
+		meta := show
+		meta.Title = e.Title
+		meta.Season = e.Season
+		meta.Episode = e.Number
+		return meta
+	}
+}
+
+// AssignEpisodes maps the titles, in order, to the run of consecutive
+// episodes whose runtimes fit them best. Runs that fit about as well, as
+// happens when all episodes have the same length, are decided by how close
+// they start to the episode index hint, for example (disc-1)*len(titles).
+// It returns the episodes by title id, or nothing if there are more titles
+// than episodes.
+func AssignEpisodes(titles []TitleInfo, episodes []Episode, hint int) map[int]Episode {
+	if len(titles) == 0 || len(titles) > len(episodes) {
+		return nil
+	}
+	titles = append([]TitleInfo(nil), titles...)
+	sort.Slice(titles, func(a, b int) bool { return titles[a].Id < titles[b].Id })
+
+	best, bestCost := 0, time.Duration(-1)
+	for offset := 0; offset+len(titles) <= len(episodes); offset++ {
+		var cost time.Duration
+		for i, t := range titles {
+			// episodes without a runtime fit anything
+			if runtime := episodes[offset+i].Runtime; runtime > 0 {
+				diff := t.Duration - runtime
+				if diff < 0 {
+					diff = -diff
+				}
+				cost += diff
+			}
+		}
+		// episode runtimes are usually rounded to minutes
+		tolerance := time.Duration(len(titles)) * time.Minute
+		switch {
+		case bestCost < 0, cost < bestCost-tolerance:
+			best, bestCost = offset, cost
+		case cost <= bestCost+tolerance && abs(offset-hint) < abs(best-hint):
+			best, bestCost = offset, cost
+		}
+	}
+
+	assigned := make(map[int]Episode, len(titles))
+	for i, t := range titles {
+		assigned[t.Id] = episodes[best+i]
+	}
+	return assigned
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
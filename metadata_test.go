@@ -0,0 +1,27 @@
+package makemkv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDiscLabel(t *testing.T) {
+	for label, want := range map[string]DiscQuery{
+		"MOVIE_DISC1":          {Title: "Movie", Disc: 1},
+		"THE_SHOW_S2_D3":       {Title: "The Show", Season: 2, Disc: 3},
+		"Some Movie (1999)":    {Title: "Some Movie", Year: 1999},
+		"BLADE_RUNNER_2049":    {Title: "Blade Runner 2049"},
+		"Show Season 4 Disc 2": {Title: "Show", Season: 4, Disc: 2},
+		"ALIEN":                {Title: "Alien"},
+		"CARS_2":               {Title: "Cars 2"},
+		"JAWS_2":               {Title: "Jaws 2"},
+		"RED_2":                {Title: "Red 2"},
+		"SHOW_S02_D1":          {Title: "Show", Season: 2, Disc: 1},
+		"ÉCOLE_DVD2":           {Title: "École", Disc: 2},
+	} {
+		var got DiscQuery
+		got.Title, got.Season, got.Disc, got.Year = parseDiscLabel(label)
+		assert.Equal(t, want, got, label)
+	}
+}
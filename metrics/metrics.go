@@ -0,0 +1,289 @@
+// Package metrics exposes job, drive and license metrics in the Prometheus
+// text format without depending on the Prometheus client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+)
+
+// upper bounds of the scan duration histogram, in seconds
+var scanBuckets = []float64{5, 10, 30, 60, 120, 300, 600}
+
+// Collector is a makemkv.Observer that keeps the metrics in memory and
+// serves them over HTTP.
+type Collector struct {
+	mu sync.Mutex
+
+	started  map[makemkv.JobKind]int
+	finished map[makemkv.JobKind]int
+	failed   map[failure]int
+	bytes    int64
+	titles   map[bool]int
+	messages map[string]int
+
+	rates map[string]*rate
+
+	scanCounts []int
+	scanCount  int
+	scanSum    float64
+
+	drives  []makemkv.DriveInfo
+	license *makemkv.LicenseInfo
+}
+
+type failure struct {
+	kind  makemkv.JobKind
+	class makemkv.ErrorClass
+}
+
+// rate tracks the estimated bytes written for a running job from its
+// progress and expected size.
+type rate struct {
+	at     time.Time
+	bytes  float64
+	perSec float64
+}
+
+func New() *Collector {
+	return &Collector{
+		started:    make(map[makemkv.JobKind]int),
+		finished:   make(map[makemkv.JobKind]int),
+		failed:     make(map[failure]int),
+		titles:     make(map[bool]int),
+		messages:   make(map[string]int),
+		rates:      make(map[string]*rate),
+		scanCounts: make([]int, len(scanBuckets)),
+	}
+}
+
+func (c *Collector) OnStart(job makemkv.ObservedJob) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started[job.Kind]++
+	if job.Kind != makemkv.JobInfo {
+		c.rates[job.Device] = &rate{at: job.Started}
+	}
+}
+
+func (c *Collector) OnProgress(job makemkv.ObservedJob, status makemkv.Status) {
+	if job.ExpectedSize == 0 || status.Max == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.rates[job.Device]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	bytes := float64(job.ExpectedSize) * float64(status.Total) / float64(status.Max)
+	// makemkvcon reports progress several times a second, only update the
+	// rate once enough time has passed to smooth it out
+	if elapsed := now.Sub(r.at).Seconds(); elapsed >= 1 {
+		if bytes >= r.bytes {
+			r.perSec = (bytes - r.bytes) / elapsed
+		}
+		r.at = now
+		r.bytes = bytes
+	}
+}
+
+func (c *Collector) OnMessage(job makemkv.ObservedJob, msg makemkv.Message) {
+	severity := "info"
+	switch {
+	case msg.IsError():
+		severity = "error"
+	case msg.IsWarning():
+		severity = "warning"
+	case msg.IsDebug():
+		return
+	}
+	c.mu.Lock()
+	c.messages[severity]++
+	c.mu.Unlock()
+}
+
+func (c *Collector) OnTitleDone(job makemkv.ObservedJob, output makemkv.OutputFile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.titles[output.Success]++
+	if output.Success {
+		c.bytes += output.Size
+	}
+}
+
+func (c *Collector) OnFinish(job makemkv.ObservedJob, result any, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.failed[failure{job.Kind, makemkv.Classify(err)}]++
+	} else {
+		c.finished[job.Kind]++
+	}
+	delete(c.rates, job.Device)
+
+	if job.Kind == makemkv.JobInfo {
+		seconds := time.Since(job.Started).Seconds()
+		c.scanCount++
+		c.scanSum += seconds
+		for i, bound := range scanBuckets {
+			if seconds <= bound {
+				c.scanCounts[i]++
+			}
+		}
+	}
+}
+
+// SetDrives records the drive states returned by makemkv.ListDrives.
+func (c *Collector) SetDrives(drives []makemkv.DriveInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drives = append([]makemkv.DriveInfo(nil), drives...)
+}
+
+// SetLicense records the license state returned by makemkv.License.
+func (c *Collector) SetLicense(license *makemkv.LicenseInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.license = license
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b strings.Builder
+	header(&b, "makemkv_jobs_started_total", "counter", "Jobs started.")
+	for _, kind := range sortedKinds(c.started) {
+		sample(&b, "makemkv_jobs_started_total", labels("kind", string(kind)), float64(c.started[kind]))
+	}
+
+	header(&b, "makemkv_jobs_finished_total", "counter", "Jobs finished successfully.")
+	for _, kind := range sortedKinds(c.finished) {
+		sample(&b, "makemkv_jobs_finished_total", labels("kind", string(kind)), float64(c.finished[kind]))
+	}
+
+	header(&b, "makemkv_jobs_failed_total", "counter", "Jobs failed, by error class.")
+	failures := make([]failure, 0, len(c.failed))
+	for f := range c.failed {
+		failures = append(failures, f)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].kind != failures[j].kind {
+			return failures[i].kind < failures[j].kind
+		}
+		return failures[i].class < failures[j].class
+	})
+	for _, f := range failures {
+		sample(&b, "makemkv_jobs_failed_total", labels("kind", string(f.kind), "class", string(f.class)), float64(c.failed[f]))
+	}
+
+	header(&b, "makemkv_titles_total", "counter", "Titles ripped, by result.")
+	sample(&b, "makemkv_titles_total", labels("result", "saved"), float64(c.titles[true]))
+	sample(&b, "makemkv_titles_total", labels("result", "failed"), float64(c.titles[false]))
+
+	header(&b, "makemkv_messages_total", "counter", "Messages from makemkvcon, by severity.")
+	for _, severity := range []string{"error", "info", "warning"} {
+		sample(&b, "makemkv_messages_total", labels("severity", severity), float64(c.messages[severity]))
+	}
+
+	header(&b, "makemkv_ripped_bytes_total", "counter", "Bytes written to successfully ripped files.")
+	sample(&b, "makemkv_ripped_bytes_total", "", float64(c.bytes))
+
+	header(&b, "makemkv_rip_rate_bytes_per_second", "gauge", "Estimated write rate of running rips.")
+	devices := make([]string, 0, len(c.rates))
+	for device := range c.rates {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	for _, device := range devices {
+		sample(&b, "makemkv_rip_rate_bytes_per_second", labels("device", device), c.rates[device].perSec)
+	}
+
+	header(&b, "makemkv_drive_state", "gauge", "Drive state as reported by makemkvcon, 2 means a disc is inserted.")
+	for _, d := range c.drives {
+		sample(&b, "makemkv_drive_state", labels("drive", strconv.Itoa(d.Index), "name", d.DriveName, "path", d.Path), float64(d.State))
+	}
+	header(&b, "makemkv_drive_disc_info", "gauge", "Disc inserted in a drive.")
+	for _, d := range c.drives {
+		if d.HasDisc() {
+			sample(&b, "makemkv_drive_disc_info", labels("drive", strconv.Itoa(d.Index), "disc", d.DiscName, "type", d.DiscType()), 1)
+		}
+	}
+
+	header(&b, "makemkv_scan_duration_seconds", "histogram", "Duration of disc scans.")
+	for i, bound := range scanBuckets {
+		sample(&b, "makemkv_scan_duration_seconds_bucket", labels("le", strconv.FormatFloat(bound, 'g', -1, 64)), float64(c.scanCounts[i]))
+	}
+	sample(&b, "makemkv_scan_duration_seconds_bucket", labels("le", "+Inf"), float64(c.scanCount))
+	sample(&b, "makemkv_scan_duration_seconds_sum", "", c.scanSum)
+	sample(&b, "makemkv_scan_duration_seconds_count", "", float64(c.scanCount))
+
+	if c.license != nil {
+		expired := 0.0
+		if c.license.Expired() {
+			expired = 1
+		}
+		header(&b, "makemkv_license_expired", "gauge", "Whether makemkvcon reported an expired key, evaluation period or version.")
+		sample(&b, "makemkv_license_expired", "", expired)
+		if !c.license.KeyExpiration.IsZero() {
+			header(&b, "makemkv_license_expiry_timestamp_seconds", "gauge", "End of the evaluation period.")
+			sample(&b, "makemkv_license_expiry_timestamp_seconds", "", float64(c.license.KeyExpiration.Unix()))
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func header(b *strings.Builder, name string, typ string, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sample(b *strings.Builder, name string, labels string, value float64) {
+	fmt.Fprintf(b, "%s%s %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// labels formats name/value pairs as a label set.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func sortedKinds(m map[makemkv.JobKind]int) []makemkv.JobKind {
+	kinds := make([]makemkv.JobKind, 0, len(m))
+	for kind := range m {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
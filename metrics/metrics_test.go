@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	c := New()
+
+	scan := makemkv.ObservedJob{Kind: makemkv.JobInfo, Device: "disc:0", Started: time.Now().Add(-20 * time.Second)}
+	c.OnStart(scan)
+	c.OnFinish(scan, &makemkv.DiscInfo{}, nil)
+
+	rip := makemkv.ObservedJob{Kind: makemkv.JobMkv, Device: "disc:0", Started: time.Now().Add(-2 * time.Second), ExpectedSize: 1000}
+	c.OnStart(rip)
+	c.rates["disc:0"].at = rip.Started
+	c.OnProgress(rip, makemkv.Status{Total: 500, Max: 1000})
+	assert.InDelta(t, 250, c.rates["disc:0"].perSec, 10)
+	c.OnMessage(rip, makemkv.Message{Code: 2003, Flags: 516})
+	c.OnTitleDone(rip, makemkv.OutputFile{Size: 1000, Success: true})
+	c.OnTitleDone(rip, makemkv.OutputFile{Size: 10})
+	c.OnFinish(rip, &makemkv.MkvResult{}, nil)
+
+	c.OnStart(rip)
+	c.OnFinish(rip, (*makemkv.MkvResult)(nil), context.Canceled)
+	c.OnStart(rip)
+	c.OnFinish(rip, nil, errors.New("boom"))
+
+	c.SetDrives([]makemkv.DriveInfo{{Index: 0, State: makemkv.DriveInserted, Flags: makemkv.DiscFlagBlurayFiles, DriveName: `BD "RE"`, DiscName: "MOVIE"}})
+	c.SetLicense(&makemkv.LicenseInfo{KeyExpiration: time.Unix(1700000000, 0)})
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`makemkv_jobs_started_total{kind="info"} 1`,
+		`makemkv_jobs_started_total{kind="mkv"} 3`,
+		`makemkv_jobs_finished_total{kind="mkv"} 1`,
+		`makemkv_jobs_failed_total{kind="mkv",class="cancelled"} 1`,
+		`makemkv_jobs_failed_total{kind="mkv",class="unknown"} 1`,
+		`makemkv_titles_total{result="saved"} 1`,
+		`makemkv_titles_total{result="failed"} 1`,
+		`makemkv_messages_total{severity="error"} 1`,
+		`makemkv_ripped_bytes_total 1000`,
+		`makemkv_drive_state{drive="0",name="BD \"RE\"",path=""} 2`,
+		`makemkv_drive_disc_info{drive="0",disc="MOVIE",type="bluray"} 1`,
+		`makemkv_scan_duration_seconds_bucket{le="10"} 0`,
+		`makemkv_scan_duration_seconds_bucket{le="30"} 1`,
+		`makemkv_scan_duration_seconds_count 1`,
+		`makemkv_license_expiry_timestamp_seconds 1.7e+09`,
+		`makemkv_license_expired 1`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.False(t, strings.Contains(body, "makemkv_rip_rate_bytes_per_second{"))
+}
@@ -2,19 +2,66 @@ package makemkv
 
 import (
 	"bufio"
+	"context"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type MkvJob struct {
-	Statuschan  chan Status
+	Statuschan chan Status
+	// receives every MSG line as it is parsed
+	Messagechan chan Message
+	// optional scan of the source, used to predict output file names
+	Info *DiscInfo
+	// extra free space required on top of the predicted file sizes
+	SpaceMargin int64
+	// allow replacing files that already exist in the destination
+	Overwrite bool
+	// angle of the title to rip, 0 for the title as given. The title of the
+	// angle is looked up in Info, which is scanned first if nil.
+	Angle       int
+	Retry       *RetryPolicy
+	Observer    Observer
 	device      Device
 	titleId     string
 	destination string
 	options     MkvOptions
 }
 
+type MkvResult struct {
+	Destination string
+	Files       []OutputFile
+	Saved       int
+	Failed      int
+	Messages    []Message
+	Attempts    []Attempt
+}
+
+type OutputFile struct {
+	TitleId       int
+	Path          string
+	Name          string
+	PredictedName string
+	Size          int64
+	Success       bool
+	Error         string
+}
+
+func (r *MkvResult) File(titleId int) *OutputFile {
+	for i := range r.Files {
+		if r.Files[i].TitleId == titleId {
+			return &r.Files[i]
+		}
+	}
+	return nil
+}
+
 func Mkv(device Device, titleId int, destination string, opts MkvOptions) *MkvJob {
 	return &MkvJob{
 		Statuschan:  nil,
@@ -35,27 +82,118 @@ func MkvAll(device Device, titleId int, destination string, opts MkvOptions) *Mk
 	}
 }
 
-func (j *MkvJob) Run() error {
-	dev := j.device.Type() + ":" + j.device.Device()
-	options := append(j.options.toStrings(), []string{"mkv", dev, j.titleId, j.destination}...)
-	cmd := exec.Command("makemkvcon", options...)
+func (j *MkvJob) Run() (*MkvResult, error) {
+	return j.RunContext(context.Background())
+}
+
+func (j *MkvJob) RunContext(ctx context.Context) (*MkvResult, error) {
+	if err := j.resolveAngle(ctx); err != nil {
+		return nil, err
+	}
+	job := newObservedJob(JobMkv, j.device, j.titleId, j.destination)
+	if j.Info != nil {
+		job.DiscName = j.Info.Name
+		for _, t := range j.Info.Titles {
+			if j.titleId == "all" || j.titleId == strconv.Itoa(t.Id) {
+				job.ExpectedSize += t.FileSize
+			}
+		}
+	}
+	if j.Observer != nil {
+		j.Observer.OnStart(job)
+	}
+	result, err := j.runContext(ctx, job)
+	if j.Observer != nil {
+		if result != nil {
+			for _, output := range result.Files {
+				j.Observer.OnTitleDone(job, output)
+			}
+		}
+		j.Observer.OnFinish(job, result, err)
+	}
+	return result, err
+}
+
+func (j *MkvJob) runContext(ctx context.Context, job ObservedJob) (*MkvResult, error) {
+	if err := j.Preflight(); err != nil {
+		return nil, err
+	}
+	if j.Retry == nil || j.Retry.MaxAttempts < 2 {
+		return j.run(ctx, j.options, job)
+	}
+
+	opts := j.options
+	var attempts []Attempt
+	var undos []func()
+	defer func() {
+		for i := len(undos) - 1; i >= 0; i-- {
+			undos[i]()
+		}
+	}()
+
+	// the result of the last attempt is returned with all attempts, also
+	// when the retries stop before the next attempt could start
+	var result *MkvResult
+	done := func(err error) (*MkvResult, error) {
+		if result != nil {
+			result.Attempts = attempts
+		}
+		return result, err
+	}
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if err := sleepContext(ctx, j.Retry.delay(attempt)); err != nil {
+				return done(err)
+			}
+			for _, escalate := range j.Retry.Escalations {
+				undo, err := escalate(attempt, &opts)
+				if undo != nil {
+					undos = append(undos, undo)
+				}
+				if err != nil {
+					return done(err)
+				}
+			}
+		}
+
+		var err error
+		result, err = j.run(ctx, opts, job)
+		a := Attempt{Number: attempt, Options: opts, Class: Classify(err)}
+		if result != nil {
+			a.Messages = result.Messages
+		}
+		if err != nil {
+			a.Error = err.Error()
+		}
+		attempts = append(attempts, a)
+
+		if err == nil || attempt >= j.Retry.MaxAttempts || !j.Retry.retryable(err) || ctx.Err() != nil {
+			return done(err)
+		}
+	}
+}
+
+func (j *MkvJob) run(ctx context.Context, opts MkvOptions, job ObservedJob) (*MkvResult, error) {
+	dev := DeviceString(j.device)
+	options := append(opts.toStrings(), []string{"mkv", dev, j.titleId, j.destination}...)
+	cmd := exec.CommandContext(ctx, opts.executable(), options...)
 
 	var scanner bufio.Scanner
 	if out, err := cmd.StdoutPipe(); err != nil {
-		return err
+		return nil, err
 	} else {
 		scanner = *bufio.NewScanner(out)
 	}
+
+	before := listMkvFiles(j.destination)
 	if err := cmd.Start(); err != nil {
-		return err
+		return nil, err
 	}
 
-	var title string
-	var channel string
-	var total int
-	var current int
-	var max int
+	result := &MkvResult{Destination: j.destination}
+	failures := make(map[int]string)
 
+	var p progress
 	for scanner.Scan() {
 		line := scanner.Text()
 		prefix, content, found := strings.Cut(line, ":")
@@ -65,30 +203,145 @@ func (j *MkvJob) Run() error {
 
 		parts := strings.Split(content, ",")
 		switch prefix {
-		case "PRGT":
-			title = parts[2]
-		case "PRGC":
-			channel = parts[2]
-		case "PRGV":
-			current, _ = strconv.Atoi(parts[0])
-			total, _ = strconv.Atoi(parts[1])
-			max, _ = strconv.Atoi(parts[2])
-			if j.Statuschan != nil {
-				select {
-				case j.Statuschan <- Status{
-					Title:   title,
-					Channel: channel,
-					Current: current,
-					Total:   total,
-					Max:     max,
-				}:
+		case "MSG":
+			msg, ok := parseMessage(content)
+			if !ok {
+				continue
+			}
+			result.Messages = append(result.Messages, msg)
+			if j.Observer != nil {
+				j.Observer.OnMessage(job, msg)
+			}
+			if j.Messagechan != nil {
+				j.Messagechan <- msg
+			}
+			if id, ok := titleSaveFailed(msg); ok {
+				failures[id] = msg.Text
+			}
+			switch msg.Code {
+			case msgAppDumpDonePartial:
+				if len(msg.Params) > 1 {
+					result.Saved, _ = strconv.Atoi(msg.Params[0])
+					result.Failed, _ = strconv.Atoi(msg.Params[1])
 				}
+			case msgAppDumpDone:
+				if len(msg.Params) > 0 {
+					result.Saved, _ = strconv.Atoi(msg.Params[0])
+				}
+			}
+		default:
+			if !p.update(prefix, parts) {
+				continue
+			}
+			if j.Observer != nil {
+				j.Observer.OnProgress(job, p.status)
+			}
+			if j.Statuschan != nil {
+				j.Statuschan <- p.status
 			}
 		}
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return err
+	err := cmd.Wait()
+	result.Files = j.collectOutputs(before, failures)
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	} else if err != nil {
+		return result, newJobError(err, result.Messages)
 	}
-	return nil
+	return result, nil
+}
+
+var titleFileRegex = regexp.MustCompile(`_t(\d+)\.mkv$`)
+
+// titleSaveFailed recognizes "Failed to save title %1 to file %2" by its
+// format, its code is not part of apdefs.h.
+func titleSaveFailed(msg Message) (int, bool) {
+	if !strings.HasPrefix(msg.Format, "Failed to save title %1") || len(msg.Params) == 0 {
+		return 0, false
+	}
+	id, err := strconv.Atoi(msg.Params[0])
+	return id, err == nil
+}
+
+// collectOutputs matches the files that appeared in the destination during
+// the rip against the predicted names and the failures reported by makemkvcon.
+func (j *MkvJob) collectOutputs(before map[string]time.Time, failures map[int]string) []OutputFile {
+	predicted := make(map[int]string)
+	if j.Info != nil {
+		for _, t := range j.Info.Titles {
+			if j.titleId == "all" || j.titleId == strconv.Itoa(t.Id) {
+				predicted[t.Id] = t.FileName
+			}
+		}
+	}
+
+	var outputs []OutputFile
+	seen := make(map[int]bool)
+	for path, modified := range listMkvFiles(j.destination) {
+		if prev, ok := before[path]; ok && modified.Equal(prev) {
+			continue
+		}
+
+		name := filepath.Base(path)
+		output := OutputFile{TitleId: -1, Path: path, Name: name}
+		for id, p := range predicted {
+			if p == name {
+				output.TitleId = id
+			}
+		}
+		if output.TitleId < 0 {
+			if m := titleFileRegex.FindStringSubmatch(name); m != nil {
+				output.TitleId, _ = strconv.Atoi(m[1])
+			}
+		}
+		if output.TitleId < 0 && j.titleId != "all" {
+			output.TitleId, _ = strconv.Atoi(j.titleId)
+		}
+		output.PredictedName = predicted[output.TitleId]
+		if info, err := os.Stat(path); err == nil {
+			output.Size = info.Size()
+		}
+		if reason, failed := failures[output.TitleId]; failed {
+			output.Error = reason
+		} else {
+			output.Success = output.Size > 0
+		}
+		seen[output.TitleId] = true
+		outputs = append(outputs, output)
+	}
+
+	for id, reason := range failures {
+		if !seen[id] {
+			outputs = append(outputs, OutputFile{TitleId: id, PredictedName: predicted[id], Error: reason})
+			seen[id] = true
+		}
+	}
+	for id, name := range predicted {
+		if !seen[id] {
+			outputs = append(outputs, OutputFile{TitleId: id, PredictedName: name, Error: "output file not found"})
+		}
+	}
+
+	sort.Slice(outputs, func(a, b int) bool {
+		return outputs[a].TitleId < outputs[b].TitleId
+	})
+	return outputs
+}
+
+func listMkvFiles(dir string) map[string]time.Time {
+	files := make(map[string]time.Time)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".mkv") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			files[filepath.Join(dir, entry.Name())] = info.ModTime()
+		}
+	}
+	return files
 }
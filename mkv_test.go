@@ -0,0 +1,111 @@
+package makemkv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectOutputs(t *testing.T) {
+	info := &DiscInfo{Titles: []TitleInfo{
+		{Id: 0, FileName: "Movie_t00.mkv"},
+		{Id: 1, FileName: "Extras_t01.mkv"},
+	}}
+	tests := []struct {
+		name     string
+		all      bool
+		titleId  int
+		info     *DiscInfo
+		existing map[string]string
+		written  map[string]string
+		failures map[int]string
+		expected []OutputFile
+	}{
+		{
+			name:     "predicted names",
+			all:      true,
+			info:     info,
+			written:  map[string]string{"Movie_t00.mkv": "movie", "Extras_t01.mkv": "extras"},
+			expected: []OutputFile{{TitleId: 0, Name: "Movie_t00.mkv", PredictedName: "Movie_t00.mkv", Size: 5, Success: true}, {TitleId: 1, Name: "Extras_t01.mkv", PredictedName: "Extras_t01.mkv", Size: 6, Success: true}},
+		},
+		{
+			name:     "title number in the name without a scan",
+			all:      true,
+			written:  map[string]string{"title_t03.mkv": "title"},
+			expected: []OutputFile{{TitleId: 3, Name: "title_t03.mkv", Size: 5, Success: true}},
+		},
+		{
+			name:     "custom name of a single title",
+			titleId:  1,
+			info:     info,
+			written:  map[string]string{"My Movie.mkv": "movie"},
+			expected: []OutputFile{{TitleId: 1, Name: "My Movie.mkv", PredictedName: "Extras_t01.mkv", Size: 5, Success: true}},
+		},
+		{
+			name:     "custom name of all titles",
+			all:      true,
+			written:  map[string]string{"My Movie.mkv": "movie"},
+			expected: []OutputFile{{TitleId: -1, Name: "My Movie.mkv", Size: 5, Success: true}},
+		},
+		{
+			name:     "existing files are left out unless rewritten",
+			all:      true,
+			existing: map[string]string{"old_t05.mkv": "old", "Movie_t00.mkv": "old"},
+			written:  map[string]string{"Movie_t00.mkv": "movie"},
+			expected: []OutputFile{{TitleId: 0, Name: "Movie_t00.mkv", Size: 5, Success: true}},
+		},
+		{
+			name:     "failures and missing files",
+			all:      true,
+			info:     info,
+			written:  map[string]string{"Movie_t00.mkv": ""},
+			failures: map[int]string{0: "Failed to save title 0", 2: "Failed to save title 2"},
+			expected: []OutputFile{{TitleId: 0, Name: "Movie_t00.mkv", PredictedName: "Movie_t00.mkv", Error: "Failed to save title 0"}, {TitleId: 1, PredictedName: "Extras_t01.mkv", Error: "output file not found"}, {TitleId: 2, Error: "Failed to save title 2"}},
+		},
+		{
+			name:     "empty file",
+			titleId:  0,
+			written:  map[string]string{"Movie_t00.mkv": ""},
+			expected: []OutputFile{{TitleId: 0, Name: "Movie_t00.mkv"}},
+		},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		past := time.Now().Add(-time.Hour)
+		for name, content := range test.existing {
+			path := filepath.Join(dir, name)
+			assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+			assert.Nil(t, os.Chtimes(path, past, past))
+		}
+		before := listMkvFiles(dir)
+		for name, content := range test.written {
+			assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		}
+
+		job := Mkv(NewDiscDevice(0), test.titleId, dir, MkvOptions{})
+		if test.all {
+			job = MkvAll(NewDiscDevice(0), 0, dir, MkvOptions{})
+		}
+		job.Info = test.info
+		for i := range test.expected {
+			if test.expected[i].Name != "" {
+				test.expected[i].Path = filepath.Join(dir, test.expected[i].Name)
+			}
+		}
+		assert.Equal(t, test.expected, job.collectOutputs(before, test.failures), test.name)
+	}
+}
+
+func TestTitleSaveFailed(t *testing.T) {
+	msg, _ := parseMessage(`5003,0,2,"Failed to save title 1 to file /out/B1_t01.mkv","Failed to save title %1 to file %2","1","/out/B1_t01.mkv"`)
+	id, ok := titleSaveFailed(msg)
+	assert.True(t, ok)
+	assert.Equal(t, 1, id)
+
+	msg, _ = parseMessage(`5004,0,2,"1 titles saved, 1 failed","%1 titles saved, %2 failed","1","1"`)
+	_, ok = titleSaveFailed(msg)
+	assert.False(t, ok)
+}
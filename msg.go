@@ -0,0 +1,112 @@
+package makemkv
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Message struct {
+	Code   int
+	Flags  int
+	Text   string
+	Format string
+	Params []string
+}
+
+func (m Message) IsError() bool {
+	return m.Flags&ap_UIMSG_BOX_MASK == ap_UIMSG_BOXERROR
+}
+
+func (m Message) IsWarning() bool {
+	return m.Flags&ap_UIMSG_BOX_MASK == ap_UIMSG_BOXWARNING
+}
+
+func (m Message) IsDebug() bool {
+	return m.Flags&ap_UIMSG_DEBUG != 0
+}
+
+// MSG:code,flags,count,message,format,param0,param1,...
+func parseMessage(content string) (Message, bool) {
+	fields := splitFields(content)
+	if len(fields) < 5 {
+		return Message{}, false
+	}
+
+	var msg Message
+	var err error
+	if msg.Code, err = strconv.Atoi(fields[0]); err != nil {
+		return Message{}, false
+	}
+	if msg.Flags, err = strconv.Atoi(fields[1]); err != nil {
+		return Message{}, false
+	}
+	count, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return Message{}, false
+	}
+	msg.Text = fields[3]
+	msg.Format = fields[4]
+	if count > 0 && len(fields) > 5 {
+		msg.Params = fields[5:]
+	}
+	return msg, true
+}
+
+// splitFields splits a robot mode line on commas, honouring double quoted
+// fields which may themselves contain commas or escaped quotes.
+func splitFields(content string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(content) && content[i+1] == '"':
+			field.WriteByte('"')
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+////////////////////////// apdefs.h //////////////////////////
+
+const (
+	ap_UIMSG_BOX_MASK   int = 3854
+	ap_UIMSG_BOXOK          = 260
+	ap_UIMSG_BOXERROR       = 516
+	ap_UIMSG_BOXWARNING     = 1028
+	ap_UIMSG_DEBUG          = 32
+	ap_UIMSG_HIDDEN         = 64
+	ap_UIMSG_EVENT          = 128
+)
+
+// message codes from apdefs.h, except msgReadError and msgOpenDiscFailed,
+// which apdefs.h leaves out. They are taken from makemkvcon output:
+//
+//	MSG:2003,0,3,"Error 'Scsi error - MEDIUM ERROR:L-EC UNCORRECTABLE ERROR' occurred while reading '/BDMV/STREAM/00055.m2ts' at offset '1048576'","Error '%1' occurred while reading '%2' at offset '%3'",...
+//	MSG:5010,0,0,"Failed to open disc","Failed to open disc"
+const (
+	msgAppStarted                    int = 1005
+	msgReadError                         = 2003
+	msgAppDumpDonePartial                = 5004
+	msgAppDumpDone                       = 5005
+	msgAppInitFailed                     = 5009
+	msgOpenDiscFailed                    = 5010
+	msgAppFolderInvalid                  = 5016
+	msgProtDemoKeyExpired                = 5021
+	msgSaveMkvFreeSpace                  = 5033
+	msgAppBackupFailed                   = 5069
+	msgAppBackupCompleted                = 5070
+	msgAppBackupCompletedHashfail        = 5079
+	msgAppIfaceRegisterCodeIncorrect     = 6077
+	msgAppIfaceRegisterCodeNotSaved      = 6078
+	msgAppIfaceRegisterCodeSaved         = 6079
+)
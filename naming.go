@@ -0,0 +1,178 @@
+package makemkv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+type Metadata struct {
+	Title   string
+	Year    int
+	Edition string
+	Show    string
+	Season  int
+	Episode int
+	ImdbId  string
+	TmdbId  string
+}
+
+type MetadataFunc func(title TitleInfo) Metadata
+
+func StaticMetadata(meta Metadata) MetadataFunc {
+	return func(TitleInfo) Metadata {
+		return meta
+	}
+}
+
+const (
+	PlexMovie       = `{{clean .Name}}{{if .Meta.Year}} ({{.Meta.Year}}){{end}}/{{clean .Name}}{{if .Meta.Year}} ({{.Meta.Year}}){{end}}{{if .Meta.Edition}} {edition-{{clean .Meta.Edition}}}{{end}}.mkv`
+	PlexEpisode     = `{{clean .Meta.Show}}/Season {{pad .Meta.Season}}/{{clean .Meta.Show}} - s{{pad .Meta.Season}}e{{pad .Meta.Episode}}.mkv`
+	JellyfinMovie   = `{{clean .Name}}{{if .Meta.Year}} ({{.Meta.Year}}){{end}}/{{clean .Name}}{{if .Meta.Year}} ({{.Meta.Year}}){{end}}{{if .Meta.Edition}} - {{clean .Meta.Edition}}{{end}}.mkv`
+	JellyfinEpisode = `{{clean .Meta.Show}}/Season {{pad .Meta.Season}}/{{clean .Meta.Show}} S{{pad .Meta.Season}}E{{pad .Meta.Episode}}.mkv`
+	KodiMovie       = `{{clean .Name}}{{if .Meta.Year}} ({{.Meta.Year}}){{end}}/{{clean .Name}}{{if .Meta.Year}} ({{.Meta.Year}}){{end}}.mkv`
+	KodiEpisode     = `{{clean .Meta.Show}}/Season {{.Meta.Season}}/{{clean .Meta.Show}} S{{pad .Meta.Season}}E{{pad .Meta.Episode}}.mkv`
+)
+
+type NameTemplate struct {
+	tmpl *template.Template
+}
+
+type nameData struct {
+	Disc  DiscInfo
+	Title TitleInfo
+	Meta  Metadata
+	// Meta.Title, falling back to the disc name
+	Name string
+}
+
+var nameFuncs = template.FuncMap{
+	"clean": cleanName,
+	"pad": func(i int) string {
+		return fmt.Sprintf("%02d", i)
+	},
+}
+
+func NewNameTemplate(text string) (*NameTemplate, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Funcs(nameFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &NameTemplate{tmpl: tmpl}, nil
+}
+
+func (t *NameTemplate) Render(disc DiscInfo, title TitleInfo, meta Metadata) (string, error) {
+	data := nameData{
+		Disc:  disc,
+		Title: title,
+		Meta:  meta,
+		Name:  meta.Title,
+	}
+	if data.Name == "" {
+		data.Name = disc.Name
+	}
+
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	path := filepath.Clean(filepath.FromSlash(b.String()))
+	if path == "." || filepath.IsAbs(path) || strings.HasPrefix(path, ".."+string(filepath.Separator)) || path == ".." {
+		return "", fmt.Errorf("makemkv: template rendered invalid path %q", b.String())
+	}
+	return path, nil
+}
+
+func cleanName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Trim(strings.TrimSpace(s), ".")
+}
+
+type Collision int
+
+const (
+	CollisionSkip Collision = iota
+	CollisionOverwrite
+	CollisionSuffix
+)
+
+var ErrDestinationExists = errors.New("makemkv: destination file already exists")
+
+type Renamer struct {
+	Root      string
+	Template  *NameTemplate
+	Collision Collision
+}
+
+// Rename moves every successfully ripped file of result to its templated
+// location below Root and updates result in place. Files that are skipped
+// because of a collision are left where they are.
+func (r *Renamer) Rename(result *MkvResult, disc DiscInfo, meta MetadataFunc) error {
+	var errs []error
+	for i := range result.Files {
+		output := &result.Files[i]
+		if !output.Success || output.TitleId < 0 || output.TitleId >= len(disc.Titles) {
+			continue
+		}
+
+		title := disc.Titles[output.TitleId]
+		rel, err := r.Template.Render(disc, title, meta(title))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		target, err := r.resolve(filepath.Join(r.Root, rel))
+		if errors.Is(err, ErrDestinationExists) {
+			continue
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := MoveFile(output.Path, target); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		output.Path = target
+		output.Name = filepath.Base(target)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Renamer) resolve(target string) (string, error) {
+	if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) {
+		return target, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	switch r.Collision {
+	case CollisionOverwrite:
+		return target, nil
+	case CollisionSuffix:
+		ext := filepath.Ext(target)
+		base := strings.TrimSuffix(target, ext)
+		for i := 1; ; i++ {
+			candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+			if _, err := os.Lstat(candidate); errors.Is(err, os.ErrNotExist) {
+				return candidate, nil
+			} else if err != nil {
+				return "", err
+			}
+		}
+	default:
+		return "", ErrDestinationExists
+	}
+}
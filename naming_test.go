@@ -0,0 +1,66 @@
+package makemkv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameTemplateRender(t *testing.T) {
+	disc := DiscInfo{Name: "MOVIE_DISC1"}
+	title := TitleInfo{Id: 0}
+
+	tmpl, err := NewNameTemplate(PlexMovie)
+	assert.Nil(t, err)
+	path, err := tmpl.Render(disc, title, Metadata{Title: "Alien: Director's Cut", Year: 1979, Edition: "Director's Cut"})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.FromSlash("Alien_ Director's Cut (1979)/Alien_ Director's Cut (1979) {edition-Director's Cut}.mkv"), path)
+
+	path, err = tmpl.Render(disc, title, Metadata{})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.FromSlash("MOVIE_DISC1/MOVIE_DISC1.mkv"), path)
+
+	tmpl, err = NewNameTemplate(JellyfinEpisode)
+	assert.Nil(t, err)
+	path, err = tmpl.Render(disc, title, Metadata{Show: "Show", Season: 1, Episode: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.FromSlash("Show/Season 01/Show S01E02.mkv"), path)
+
+	tmpl, err = NewNameTemplate(`../{{.Name}}.mkv`)
+	assert.Nil(t, err)
+	_, err = tmpl.Render(disc, title, Metadata{})
+	assert.NotNil(t, err)
+}
+
+func TestRenamerCollision(t *testing.T) {
+	dir := t.TempDir()
+	tmpl, _ := NewNameTemplate(KodiMovie)
+	disc := DiscInfo{Name: "Movie", Titles: []TitleInfo{{Id: 0}}}
+
+	existing := filepath.Join(dir, "library", "Movie", "Movie.mkv")
+	os.MkdirAll(filepath.Dir(existing), 0755)
+	os.WriteFile(existing, []byte("old"), 0644)
+
+	for _, tc := range []struct {
+		collision Collision
+		expected  string
+	}{
+		{CollisionSkip, filepath.Join(dir, "rip", "title_t00.mkv")},
+		{CollisionSuffix, filepath.Join(dir, "library", "Movie", "Movie (1).mkv")},
+		{CollisionOverwrite, existing},
+	} {
+		src := filepath.Join(dir, "rip", "title_t00.mkv")
+		os.MkdirAll(filepath.Dir(src), 0755)
+		os.WriteFile(src, []byte("new"), 0644)
+
+		result := &MkvResult{Files: []OutputFile{{TitleId: 0, Path: src, Success: true}}}
+		renamer := Renamer{Root: filepath.Join(dir, "library"), Template: tmpl, Collision: tc.collision}
+		assert.Nil(t, renamer.Rename(result, disc, StaticMetadata(Metadata{})))
+		assert.Equal(t, tc.expected, result.Files[0].Path)
+
+		content, _ := os.ReadFile(tc.expected)
+		assert.Equal(t, "new", string(content))
+	}
+}
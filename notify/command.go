@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+// Command runs a program for every event. Each argument is a template
+// rendered with the Event, the event is also written to stdin as JSON.
+// Arguments are passed to the program as is, wrap it in "sh -c" to use a
+// shell.
+type Command struct {
+	Args []*template.Template
+}
+
+// NewCommand parses every argument as a template.
+func NewCommand(args ...string) (*Command, error) {
+	if len(args) == 0 {
+		return nil, errors.New("notify: command is empty")
+	}
+	c := &Command{}
+	for i, arg := range args {
+		tmpl, err := ParseTemplate(fmt.Sprintf("arg%d", i), arg)
+		if err != nil {
+			return nil, err
+		}
+		c.Args = append(c.Args, tmpl)
+	}
+	return c, nil
+}
+
+func (c *Command) Notify(ctx context.Context, e Event) error {
+	args := make([]string, len(c.Args))
+	for i, tmpl := range c.Args {
+		arg, err := render(tmpl, e)
+		if err != nil {
+			return err
+		}
+		args[i] = arg
+	}
+	stdin, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("notify: %s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("notify: %s: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	defaultSubject = template.Must(ParseTemplate("subject", `{{if .Success}}Ripped{{else}}Failed to rip{{end}} {{or .DiscName .Device}}`))
+	defaultBody    = template.Must(ParseTemplate("body", `{{.Kind}} of {{or .DiscName .Device}} {{if .Success}}finished{{else}}failed{{end}} after {{duration .Duration}}.
+{{if .Error}}
+Error ({{.Class}}): {{.Error}}
+{{end}}{{range .Titles}}
+title {{.Id}}: {{if .Success}}{{.Path}} ({{size .Size}}){{else}}failed {{.Error}}{{end}}{{end}}
+`))
+)
+
+// Email sends a plain text mail through an SMTP server.
+type Email struct {
+	// host:port of the server
+	Addr string
+	// optional, for example smtp.PlainAuth
+	Auth smtp.Auth
+	From string
+	To   []string
+	// default to a short summary of the event
+	Subject *template.Template
+	Body    *template.Template
+}
+
+func (m *Email) Notify(ctx context.Context, e Event) error {
+	if len(m.To) == 0 {
+		return errors.New("notify: email has no recipients")
+	}
+	msg, err := m.message(e, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, give up waiting on it instead
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Addr, m.Auth, m.From, m.To, msg)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Email) message(e Event, now time.Time) ([]byte, error) {
+	subjectTmpl, bodyTmpl := m.Subject, m.Body
+	if subjectTmpl == nil {
+		subjectTmpl = defaultSubject
+	}
+	if bodyTmpl == nil {
+		bodyTmpl = defaultBody
+	}
+	subject, err := render(subjectTmpl, e)
+	if err != nil {
+		return nil, err
+	}
+	body, err := render(bodyTmpl, e)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
+
+// headerValue keeps a templated value from injecting extra headers.
+func headerValue(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
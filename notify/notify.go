@@ -0,0 +1,226 @@
+// Package notify sends notifications when makemkv jobs finish, to webhooks,
+// shell commands or by email.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+)
+
+type Event struct {
+	Kind        makemkv.JobKind
+	Device      string
+	DiscName    string
+	Destination string
+	Titles      []Title
+	Started     time.Time
+	Duration    time.Duration
+	Success     bool
+	Error       string             `json:",omitempty"`
+	Class       makemkv.ErrorClass `json:",omitempty"`
+}
+
+type Title struct {
+	Id      int
+	Name    string
+	Path    string `json:",omitempty"`
+	Size    int64
+	Success bool
+	Error   string `json:",omitempty"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// NewEvent builds the event for a finished job as seen by an observer.
+func NewEvent(job makemkv.ObservedJob, result any, err error) Event {
+	e := Event{
+		Kind:        job.Kind,
+		Device:      job.Device,
+		DiscName:    job.DiscName,
+		Destination: job.Destination,
+		Started:     job.Started,
+		Duration:    time.Since(job.Started),
+		Success:     err == nil,
+	}
+	if err != nil {
+		e.Error = err.Error()
+		e.Class = makemkv.Classify(err)
+	}
+	switch r := result.(type) {
+	case *makemkv.MkvResult:
+		if r != nil {
+			e.Titles = titles(r)
+		}
+	case *makemkv.DiscInfo:
+		if r != nil && e.DiscName == "" {
+			e.DiscName = r.Name
+		}
+	}
+	return e
+}
+
+// QueueEvent builds the event for a job that finished in a makemkv.Queue,
+// for use from Queue.OnDone.
+func QueueEvent(job makemkv.QueueJob) Event {
+	e := Event{
+		Kind:        job.Kind,
+		Device:      job.Device,
+		Destination: job.Destination,
+		Started:     job.Started,
+		Duration:    job.Finished.Sub(job.Started),
+		Success:     job.State == makemkv.JobDone,
+		Error:       job.Error,
+		Class:       job.Class,
+	}
+	if job.Info != nil {
+		e.DiscName = job.Info.Name
+	}
+	if job.Result != nil {
+		e.Titles = titles(job.Result)
+	}
+	return e
+}
+
+func titles(result *makemkv.MkvResult) []Title {
+	titles := make([]Title, len(result.Files))
+	for i, f := range result.Files {
+		titles[i] = Title{
+			Id:      f.TitleId,
+			Name:    f.Name,
+			Path:    f.Path,
+			Size:    f.Size,
+			Success: f.Success,
+			Error:   f.Error,
+		}
+		if titles[i].Name == "" {
+			titles[i].Name = f.PredictedName
+		}
+	}
+	return titles
+}
+
+// Dispatcher is a makemkv.Observer that sends an event to all notifiers
+// when a job ends. Notifications are sent in the background, Wait blocks
+// until they are done.
+type Dispatcher struct {
+	makemkv.NopObserver
+
+	Notifiers []Notifier
+	// job kinds to notify about, mkv and backup jobs if empty
+	Kinds []makemkv.JobKind
+	// only notify about jobs that failed
+	FailuresOnly bool
+	// called with the errors of notifiers that gave up
+	OnError func(n Notifier, err error)
+	// bounds every notification, including retries; one minute if zero
+	Timeout time.Duration
+
+	wg sync.WaitGroup
+}
+
+func (d *Dispatcher) OnFinish(job makemkv.ObservedJob, result any, err error) {
+	if !d.wants(job.Kind) || (d.FailuresOnly && err == nil) {
+		return
+	}
+	d.Send(NewEvent(job, result, err))
+}
+
+// Send notifies all notifiers of e in the background.
+func (d *Dispatcher) Send(e Event) {
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	for _, n := range d.Notifiers {
+		d.wg.Add(1)
+		go func(n Notifier) {
+			defer d.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := n.Notify(ctx, e); err != nil && d.OnError != nil {
+				d.OnError(n, err)
+			}
+		}(n)
+	}
+}
+
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) wants(kind makemkv.JobKind) bool {
+	if len(d.Kinds) == 0 {
+		return kind == makemkv.JobMkv || kind == makemkv.JobBackup
+	}
+	for _, k := range d.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry retries a notifier with exponential backoff.
+type Retry struct {
+	Notifier Notifier
+	Attempts int
+	// delay before the second attempt, doubled for every attempt after
+	Backoff time.Duration
+}
+
+func (r Retry) Notify(ctx context.Context, e Event) error {
+	delay := r.Backoff
+	var errs []error
+	for attempt := 1; ; attempt++ {
+		err := r.Notifier.Notify(ctx, e)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		if attempt >= r.Attempts {
+			return errors.Join(errs...)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(append(errs, ctx.Err())...)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"size": makemkv.FormatSize,
+}
+
+// ParseTemplate parses a payload, command or mail template. Besides the
+// standard functions it has json, encoding its argument, duration, rounding
+// to seconds, and size, printing a byte count in binary units.
+func ParseTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+func render(tmpl *template.Template, e Event) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, e); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
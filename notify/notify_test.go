@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvent() Event {
+	job := makemkv.ObservedJob{Kind: makemkv.JobMkv, Device: "disc:0", DiscName: "MOVIE", Started: time.Now().Add(-time.Hour)}
+	result := &makemkv.MkvResult{Files: []makemkv.OutputFile{
+		{TitleId: 0, Path: "/media/title_t00.mkv", Name: "title_t00.mkv", Size: 3 << 30, Success: true},
+		{TitleId: 1, PredictedName: "title_t01.mkv", Error: "read error"},
+	}}
+	return NewEvent(job, result, &makemkv.JobError{Class: makemkv.ClassSource, Err: errors.New("exit status 1")})
+}
+
+func TestWebhook(t *testing.T) {
+	var calls atomic.Int32
+	var got Event
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail the first attempt to exercise the retry
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer ts.Close()
+
+	var failed error
+	d := &Dispatcher{
+		Notifiers: []Notifier{Retry{
+			Notifier: &Webhook{URL: ts.URL, Headers: map[string]string{"Authorization": "Bearer x"}},
+			Attempts: 3,
+			Backoff:  time.Millisecond,
+		}},
+		OnError: func(n Notifier, err error) { failed = err },
+	}
+	e := testEvent()
+	d.OnFinish(makemkv.ObservedJob{Kind: makemkv.JobInfo}, nil, nil)
+	d.Send(e)
+	d.Wait()
+
+	assert.Nil(t, failed)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, "Bearer x", auth)
+	assert.Equal(t, "MOVIE", got.DiscName)
+	assert.Equal(t, makemkv.ClassSource, got.Class)
+	assert.False(t, got.Success)
+	assert.Equal(t, 2, len(got.Titles))
+	assert.Equal(t, "title_t01.mkv", got.Titles[1].Name)
+	assert.True(t, got.Duration >= time.Hour)
+}
+
+func TestWebhookPayload(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer ts.Close()
+
+	payload, err := ParseTemplate("payload", `{"text": {{json (printf "%s: %s" .DiscName .Class)}}}`)
+	assert.Nil(t, err)
+	w := &Webhook{URL: ts.URL, Payload: payload}
+	assert.Nil(t, w.Notify(context.Background(), testEvent()))
+	assert.Equal(t, `{"text": "MOVIE: source"}`, body)
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	assert.NotNil(t, w.Notify(context.Background(), testEvent()))
+}
+
+func TestCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	cmd, err := NewCommand("sh", "-c", `cat > "$0"; printf '\n%s\n' "$1" >> "$0"`, out, "{{.DiscName}} {{.Kind}}")
+	assert.Nil(t, err)
+	assert.Nil(t, cmd.Notify(context.Background(), testEvent()))
+
+	data, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, "MOVIE mkv", lines[len(lines)-1])
+	var e Event
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &e))
+	assert.Equal(t, "disc:0", e.Device)
+
+	cmd, _ = NewCommand("sh", "-c", "echo broken >&2; exit 3")
+	err = cmd.Notify(context.Background(), testEvent())
+	assert.ErrorContains(t, err, "broken")
+}
+
+func TestEmail(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go fakeSmtp(l, received)
+
+	m := &Email{Addr: l.Addr().String(), From: "rip@example.com", To: []string{"me@example.com"}}
+	assert.Nil(t, m.Notify(context.Background(), testEvent()))
+
+	msg := <-received
+	assert.Contains(t, msg, "Subject: Failed to rip MOVIE\r\n")
+	assert.Contains(t, msg, "mkv of MOVIE failed after 1h0m0s.\r\n")
+	assert.Contains(t, msg, "title 0: /media/title_t00.mkv (3.0 GiB)\r\n")
+	assert.Contains(t, msg, "title 1: failed read error\r\n")
+	assert.Contains(t, msg, "Error (source): makemkv: source error: exit status 1\r\n")
+
+	// headers are ASCII, other names are encoded words
+	e := testEvent()
+	e.DiscName = "Amélie"
+	message, err := m.message(e, time.Now())
+	assert.Nil(t, err)
+	assert.Contains(t, string(message), "Subject: =?utf-8?q?Failed_to_rip_Am=C3=A9lie?=\r\n")
+}
+
+// fakeSmtp accepts one mail and sends its data to received.
+func fakeSmtp(l net.Listener, received chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+	reply("220 localhost")
+	var data strings.Builder
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				received <- data.String()
+				reply("250 ok")
+			} else {
+				data.WriteString(line)
+			}
+			continue
+		}
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case cmd == "DATA":
+			inData = true
+			reply("354 go ahead")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestQueueEvent(t *testing.T) {
+	started := time.Now().Add(-time.Minute)
+	e := QueueEvent(makemkv.QueueJob{
+		Kind:     makemkv.JobMkv,
+		Device:   "disc:0",
+		State:    makemkv.JobFailed,
+		Error:    "makemkv: insufficient disk space",
+		Class:    makemkv.ClassDestination,
+		Started:  started,
+		Finished: started.Add(time.Minute),
+		Info:     &makemkv.DiscInfo{Name: "MOVIE"},
+	})
+	assert.False(t, e.Success)
+	assert.Equal(t, makemkv.ClassDestination, e.Class)
+	assert.Equal(t, "MOVIE", e.DiscName)
+	assert.Equal(t, time.Minute, e.Duration)
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+)
+
+// Webhook POSTs the event to URL, as JSON unless Payload is set.
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+	// optional template rendering the request body from the Event
+	Payload *template.Template
+	Client  *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	var body []byte
+	if w.Payload != nil {
+		s, err := render(w.Payload, e)
+		if err != nil {
+			return err
+		}
+		body = []byte(s)
+	} else {
+		var err error
+		if body, err = json.Marshal(e); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify: webhook %s returned %s", w.URL, resp.Status)
+	}
+	return nil
+}
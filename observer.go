@@ -0,0 +1,237 @@
+package makemkv
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ObservedJob identifies the job an Observer is called for.
+type ObservedJob struct {
+	Kind        JobKind
+	Device      string
+	TitleId     string
+	Destination string
+	Started     time.Time
+	// name of the disc, if the job has a scan of it
+	DiscName string
+	// sum of the scanned sizes of the titles being ripped, 0 if unknown
+	ExpectedSize int64
+}
+
+// Observer is called on the goroutine running the job and must not block.
+type Observer interface {
+	OnStart(job ObservedJob)
+	// called for every progress value, Status.Title and Status.Channel name
+	// the current phase
+	OnProgress(job ObservedJob, status Status)
+	OnMessage(job ObservedJob, msg Message)
+	// called for every output of an mkv job once makemkvcon has exited
+	OnTitleDone(job ObservedJob, output OutputFile)
+	// result is the *DiscInfo, *MkvResult or *BackupResult of the job, and
+	// may be nil
+	OnFinish(job ObservedJob, result any, err error)
+}
+
+// NopObserver can be embedded to implement only some of the callbacks.
+type NopObserver struct{}
+
+func (NopObserver) OnStart(ObservedJob)                 {}
+func (NopObserver) OnProgress(ObservedJob, Status)      {}
+func (NopObserver) OnMessage(ObservedJob, Message)      {}
+func (NopObserver) OnTitleDone(ObservedJob, OutputFile) {}
+func (NopObserver) OnFinish(ObservedJob, any, error)    {}
+
+// Observers calls each of its observers in order.
+type Observers []Observer
+
+func (o Observers) OnStart(job ObservedJob) {
+	for _, obs := range o {
+		obs.OnStart(job)
+	}
+}
+
+func (o Observers) OnProgress(job ObservedJob, status Status) {
+	for _, obs := range o {
+		obs.OnProgress(job, status)
+	}
+}
+
+func (o Observers) OnMessage(job ObservedJob, msg Message) {
+	for _, obs := range o {
+		obs.OnMessage(job, msg)
+	}
+}
+
+func (o Observers) OnTitleDone(job ObservedJob, output OutputFile) {
+	for _, obs := range o {
+		obs.OnTitleDone(job, output)
+	}
+}
+
+func (o Observers) OnFinish(job ObservedJob, result any, err error) {
+	for _, obs := range o {
+		obs.OnFinish(job, result, err)
+	}
+}
+
+func newObservedJob(kind JobKind, device Device, titleId string, destination string) ObservedJob {
+	return ObservedJob{
+		Kind:        kind,
+		Device:      DeviceString(device),
+		TitleId:     titleId,
+		Destination: destination,
+		Started:     time.Now(),
+	}
+}
+
+// phases remembers the last progress phase of each running job, so the
+// logging observers only report changes.
+type phases struct {
+	mu   sync.Mutex
+	last map[ObservedJob]Status
+}
+
+func (p *phases) changed(job ObservedJob, status Status) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.last == nil {
+		p.last = make(map[ObservedJob]Status)
+	}
+	last, ok := p.last[job]
+	p.last[job] = status
+	return !ok || last.Title != status.Title || last.Channel != status.Channel
+}
+
+func (p *phases) done(job ObservedJob) {
+	p.mu.Lock()
+	delete(p.last, job)
+	p.mu.Unlock()
+}
+
+type slogObserver struct {
+	logger *slog.Logger
+	phases phases
+}
+
+// SlogObserver logs job events to logger. Progress is only logged when the
+// phase changes, messages are logged at the level matching their flags.
+func SlogObserver(logger *slog.Logger) Observer {
+	return &slogObserver{logger: logger}
+}
+
+func (o *slogObserver) attrs(job ObservedJob) slog.Attr {
+	attrs := []any{slog.String("kind", string(job.Kind)), slog.String("device", job.Device)}
+	if job.TitleId != "" {
+		attrs = append(attrs, slog.String("title", job.TitleId))
+	}
+	return slog.Group("job", attrs...)
+}
+
+func (o *slogObserver) OnStart(job ObservedJob) {
+	o.logger.Info("job started", o.attrs(job), slog.String("destination", job.Destination))
+}
+
+func (o *slogObserver) OnProgress(job ObservedJob, status Status) {
+	if o.phases.changed(job, status) {
+		o.logger.Info("job progress", o.attrs(job), slog.String("phase", status.Title), slog.String("step", status.Channel))
+	}
+}
+
+func (o *slogObserver) OnMessage(job ObservedJob, msg Message) {
+	level := slog.LevelInfo
+	switch {
+	case msg.IsError():
+		level = slog.LevelError
+	case msg.IsWarning():
+		level = slog.LevelWarn
+	case msg.IsDebug():
+		level = slog.LevelDebug
+	}
+	o.logger.Log(context.Background(), level, msg.Text, o.attrs(job), slog.Int("code", msg.Code))
+}
+
+func (o *slogObserver) OnTitleDone(job ObservedJob, output OutputFile) {
+	if output.Success {
+		o.logger.Info("title saved", o.attrs(job), slog.Int("title", output.TitleId), slog.String("path", output.Path), slog.Int64("size", output.Size))
+	} else {
+		o.logger.Error("title failed", o.attrs(job), slog.Int("title", output.TitleId), slog.String("error", output.Error))
+	}
+}
+
+func (o *slogObserver) OnFinish(job ObservedJob, result any, err error) {
+	o.phases.done(job)
+	elapsed := slog.Duration("elapsed", time.Since(job.Started))
+	if err != nil {
+		o.logger.Error("job failed", o.attrs(job), elapsed, slog.String("class", string(Classify(err))), slog.String("error", err.Error()))
+	} else {
+		o.logger.Info("job finished", o.attrs(job), elapsed)
+	}
+}
+
+// ObserverEvent is one line written by EventLogObserver.
+type ObserverEvent struct {
+	Time    time.Time
+	Event   string
+	Job     ObservedJob
+	Status  *Status     `json:",omitempty"`
+	Message *Message    `json:",omitempty"`
+	Output  *OutputFile `json:",omitempty"`
+	Result  any         `json:",omitempty"`
+	Error   string      `json:",omitempty"`
+	Class   ErrorClass  `json:",omitempty"`
+}
+
+type eventLogObserver struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	phases phases
+}
+
+// EventLogObserver writes every event as a JSON line to w. Like
+// SlogObserver it only writes progress when the phase changes.
+func EventLogObserver(w io.Writer) Observer {
+	return &eventLogObserver{enc: json.NewEncoder(w)}
+}
+
+func (o *eventLogObserver) write(e ObserverEvent) {
+	e.Time = time.Now()
+	o.mu.Lock()
+	o.enc.Encode(e)
+	o.mu.Unlock()
+}
+
+func (o *eventLogObserver) OnStart(job ObservedJob) {
+	o.write(ObserverEvent{Event: "start", Job: job})
+}
+
+func (o *eventLogObserver) OnProgress(job ObservedJob, status Status) {
+	if o.phases.changed(job, status) {
+		o.write(ObserverEvent{Event: "progress", Job: job, Status: &status})
+	}
+}
+
+func (o *eventLogObserver) OnMessage(job ObservedJob, msg Message) {
+	o.write(ObserverEvent{Event: "message", Job: job, Message: &msg})
+}
+
+func (o *eventLogObserver) OnTitleDone(job ObservedJob, output OutputFile) {
+	o.write(ObserverEvent{Event: "title", Job: job, Output: &output})
+}
+
+func (o *eventLogObserver) OnFinish(job ObservedJob, result any, err error) {
+	o.phases.done(job)
+	e := ObserverEvent{Event: "finish", Job: job}
+	if err != nil {
+		e.Error = err.Error()
+		e.Class = Classify(err)
+	}
+	// the disc info and messages are already in the log, keep the summary
+	if r, ok := result.(*MkvResult); ok && r != nil {
+		e.Result = struct{ Saved, Failed int }{r.Saved, r.Failed}
+	}
+	o.write(e)
+}
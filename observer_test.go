@@ -0,0 +1,102 @@
+package makemkv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingObserver struct {
+	NopObserver
+	messages int
+	finished int
+}
+
+func (o *countingObserver) OnMessage(ObservedJob, Message)   { o.messages++ }
+func (o *countingObserver) OnFinish(ObservedJob, any, error) { o.finished++ }
+
+func TestObservers(t *testing.T) {
+	var log bytes.Buffer
+	var events bytes.Buffer
+	counting := &countingObserver{}
+	o := Observers{counting, SlogObserver(slog.New(slog.NewTextHandler(&log, nil))), EventLogObserver(&events)}
+
+	job := newObservedJob(JobMkv, NewDiscDevice(0), "1", "/tmp")
+	o.OnStart(job)
+	o.OnProgress(job, Status{Title: "Saving to MKV file", Total: 1})
+	o.OnProgress(job, Status{Title: "Saving to MKV file", Total: 2})
+	o.OnMessage(job, Message{Code: msgReadError, Flags: ap_UIMSG_BOXERROR, Text: "read error"})
+	o.OnTitleDone(job, OutputFile{TitleId: 1, Path: "/tmp/title_t01.mkv", Success: true})
+	o.OnFinish(job, &MkvResult{Saved: 1}, errors.New("boom"))
+
+	assert.Equal(t, 1, counting.messages)
+	assert.Equal(t, 1, counting.finished)
+
+	assert.Equal(t, 1, strings.Count(log.String(), "job progress"))
+	assert.Contains(t, log.String(), "level=ERROR msg=\"read error\"")
+	assert.Contains(t, log.String(), "class=unknown")
+
+	var kinds []string
+	for _, line := range strings.Split(strings.TrimSpace(events.String()), "\n") {
+		var e ObserverEvent
+		assert.Nil(t, json.Unmarshal([]byte(line), &e))
+		assert.Equal(t, "disc:0", e.Job.Device)
+		kinds = append(kinds, e.Event)
+	}
+	assert.Equal(t, []string{"start", "progress", "message", "title", "finish"}, kinds)
+}
+
+type channelObserver struct {
+	NopObserver
+	events chan string
+}
+
+func (o *channelObserver) OnProgress(_ ObservedJob, s Status) {
+	o.events <- fmt.Sprintf("progress %d/%d", s.Total, s.Max)
+}
+func (o *channelObserver) OnMessage(_ ObservedJob, m Message) { o.events <- "message " + m.Text }
+
+func TestInfoJobObserver(t *testing.T) {
+	dir := t.TempDir()
+	gate := filepath.Join(dir, "gate")
+	bin := filepath.Join(dir, "makemkvcon")
+	// the scan only finishes once the test has seen its first events
+	assert.Nil(t, os.WriteFile(bin, []byte(`#!/bin/sh
+echo 'MSG:1005,0,1,"MakeMKV started","%1 started","MakeMKV"'
+echo 'PRGV:1,2,65536'
+while [ ! -e `+gate+` ]; do sleep 0.01; done
+echo 'TCOUNT:1'
+echo 'TINFO:0,16,0,"00800.mpls"'
+`), 0755))
+
+	o := &channelObserver{events: make(chan string, 16)}
+	job := Info(NewDiscDevice(0), MkvOptions{Executable: bin})
+	job.Observer = o
+	done := make(chan *DiscInfo)
+	go func() {
+		info, err := job.Run()
+		assert.Nil(t, err)
+		done <- info
+	}()
+
+	for _, expected := range []string{"message MakeMKV started", "progress 2/65536"} {
+		select {
+		case e := <-o.events:
+			assert.Equal(t, expected, e)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event before makemkvcon exited")
+		}
+	}
+	assert.Nil(t, os.WriteFile(gate, nil, 0644))
+	info := <-done
+	assert.Equal(t, "00800.mpls", info.Titles[0].SourceFileName)
+}
@@ -0,0 +1,235 @@
+// Package pipeline runs the steps that follow a rip, like verifying,
+// renaming, transcoding and moving the files, as a chain of stages.
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aravance/go-makemkv"
+)
+
+// Item is what flows through the stages, each stage returns the item the
+// next one receives.
+type Item struct {
+	// identifies the item in the pipeline state, derived from the disc and
+	// the output files if empty
+	Id     string
+	Info   makemkv.DiscInfo
+	Result makemkv.MkvResult
+	// sha256 of the output files by path, filled by Checksum
+	Checksums map[string]string `json:",omitempty"`
+	// free form values stages can pass on
+	Values map[string]string `json:",omitempty"`
+}
+
+func NewItem(info makemkv.DiscInfo, result makemkv.MkvResult) Item {
+	return Item{Info: info, Result: result}
+}
+
+func (i Item) id() string {
+	if i.Id != "" {
+		return i.Id
+	}
+	// the fingerprint tells apart discs with generic names like "DVD_VIDEO",
+	// the paths two rips of the same disc
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\n", makemkv.Fingerprint(i.Info), i.Info.Name, i.Result.Destination)
+	for _, f := range i.Result.Files {
+		fmt.Fprintf(h, "%d\x00%s\n", f.TitleId, f.Path)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+type Stage interface {
+	Name() string
+	Run(ctx context.Context, item Item) (Item, error)
+}
+
+type funcStage struct {
+	name string
+	fn   func(ctx context.Context, item Item) (Item, error)
+}
+
+func (s funcStage) Name() string { return s.name }
+
+func (s funcStage) Run(ctx context.Context, item Item) (Item, error) { return s.fn(ctx, item) }
+
+// Func turns fn into a stage.
+func Func(name string, fn func(ctx context.Context, item Item) (Item, error)) Stage {
+	return funcStage{name, fn}
+}
+
+// StageError wraps the error of the stage that stopped an item.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return "pipeline: stage " + e.Stage + ": " + e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+type Pipeline struct {
+	Stages []Stage
+	// number of items run at the same time by RunAll, 1 if zero
+	Concurrency int
+	// directory keeping the progress of every item. When set, an item that
+	// is run again continues after the last stage it completed.
+	StateDir string
+}
+
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{Stages: stages}
+}
+
+type state struct {
+	Completed []string
+	Item      Item
+}
+
+// Run passes item through every stage in order and returns the result of
+// the last one.
+func (p *Pipeline) Run(ctx context.Context, item Item) (Item, error) {
+	if err := p.checkStages(); err != nil {
+		return item, err
+	}
+	id := item.id()
+	st, err := p.load(id)
+	if err != nil {
+		return item, err
+	}
+	if st != nil {
+		item = st.Item
+	} else {
+		st = &state{}
+	}
+
+	for i, stage := range p.Stages {
+		if i < len(st.Completed) {
+			if st.Completed[i] != stage.Name() {
+				return item, fmt.Errorf("pipeline: state of %s was written by different stages", id)
+			}
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return item, err
+		}
+
+		out, err := stage.Run(ctx, item)
+		if err != nil {
+			return item, &StageError{Stage: stage.Name(), Err: err}
+		}
+		item = out
+		st.Completed = append(st.Completed, stage.Name())
+		st.Item = item
+		if err := p.save(id, st); err != nil {
+			return item, err
+		}
+	}
+	return item, nil
+}
+
+type Outcome struct {
+	Item Item
+	Err  error
+}
+
+// RunAll runs the items concurrently, up to Concurrency at a time. The
+// outcomes are in the order of items.
+func (p *Pipeline) RunAll(ctx context.Context, items []Item) []Outcome {
+	concurrency := p.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	outcomes := make([]Outcome, len(items))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item Item) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				outcomes[i] = Outcome{item, ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+			out, err := p.Run(ctx, item)
+			outcomes[i] = Outcome{out, err}
+		}(i, item)
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// Reset forgets the progress of item, so it runs through all stages again.
+func (p *Pipeline) Reset(item Item) error {
+	if p.StateDir == "" {
+		return nil
+	}
+	err := os.Remove(p.statePath(item.id()))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (p *Pipeline) checkStages() error {
+	seen := make(map[string]bool)
+	for _, stage := range p.Stages {
+		if seen[stage.Name()] {
+			return fmt.Errorf("pipeline: duplicate stage %q", stage.Name())
+		}
+		seen[stage.Name()] = true
+	}
+	return nil
+}
+
+func (p *Pipeline) statePath(id string) string {
+	return filepath.Join(p.StateDir, id+".json")
+}
+
+func (p *Pipeline) load(id string) (*state, error) {
+	if p.StateDir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(p.statePath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("pipeline: reading state of %s: %w", id, err)
+	}
+	return &st, nil
+}
+
+func (p *Pipeline) save(id string, st *state) error {
+	if p.StateDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(p.StateDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return makemkv.WriteFileAtomic(p.statePath(id), data)
+}
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/stretchr/testify/assert"
+)
+
+func testItem(t *testing.T, name string) Item {
+	dir := t.TempDir()
+	path := filepath.Join(dir, name+"_t00.mkv")
+	assert.Nil(t, os.WriteFile(path, []byte("matroska"), 0644))
+	return NewItem(
+		makemkv.DiscInfo{Name: name, Titles: []makemkv.TitleInfo{{Id: 0}}},
+		makemkv.MkvResult{Destination: dir, Files: []makemkv.OutputFile{
+			{TitleId: 0, Path: path, Name: filepath.Base(path), Size: 8, Success: true},
+			{TitleId: 1, Error: "read error"},
+		}},
+	)
+}
+
+func TestStages(t *testing.T) {
+	item := testItem(t, "MOVIE")
+	nas := t.TempDir()
+
+	transcode := &Command{
+		StageName:   "transcode",
+		Args:        []string{"cp", "{{.File.Path}}", "{{trimext .File.Path}}.mp4"},
+		PerFile:     true,
+		Output:      "{{trimext .File.Path}}.mp4",
+		RemoveInput: true,
+	}
+	p := New(transcode, Checksum(true), Copy(nas))
+	out, err := p.Run(context.Background(), item)
+	assert.Nil(t, err)
+
+	copied := filepath.Join(nas, "MOVIE_t00.mp4")
+	assert.Equal(t, copied, out.Result.Files[0].Path)
+	assert.Equal(t, "MOVIE_t00.mp4", out.Result.Files[0].Name)
+	assert.Equal(t, "", out.Result.Files[1].Path)
+	assert.FileExists(t, copied)
+	assert.NoFileExists(t, item.Result.Files[0].Path)
+
+	source := filepath.Join(item.Result.Destination, "MOVIE_t00.mp4")
+	assert.Equal(t, out.Checksums[source], out.Checksums[copied])
+	assert.Equal(t, 64, len(out.Checksums[copied]))
+	sidecar, err := os.ReadFile(source + ".sha256")
+	assert.Nil(t, err)
+	assert.Equal(t, out.Checksums[source]+"  MOVIE_t00.mp4\n", string(sidecar))
+	assert.FileExists(t, copied+".sha256")
+}
+
+func TestMove(t *testing.T) {
+	item := testItem(t, "MOVIE")
+	nas := t.TempDir()
+	p := New(Checksum(true), Move(nas))
+	out, err := p.Run(context.Background(), item)
+	assert.Nil(t, err)
+
+	moved := filepath.Join(nas, "MOVIE_t00.mkv")
+	assert.Equal(t, moved, out.Result.Files[0].Path)
+	assert.FileExists(t, moved+".sha256")
+	assert.NoFileExists(t, item.Result.Files[0].Path+".sha256")
+
+	// a second disc of the same name does not replace the first
+	again := testItem(t, "MOVIE")
+	_, err = New(Move(nas)).Run(context.Background(), again)
+	assert.True(t, errors.Is(err, makemkv.ErrDestinationExists))
+	assert.FileExists(t, again.Result.Files[0].Path)
+	data, err := os.ReadFile(moved + ".sha256")
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), out.Checksums[moved])
+}
+
+func TestResume(t *testing.T) {
+	state := t.TempDir()
+	item := testItem(t, "MOVIE")
+
+	counts := make(map[string]int)
+	fail := true
+	stage := func(name string) Stage {
+		return Func(name, func(ctx context.Context, item Item) (Item, error) {
+			counts[name]++
+			if name == "second" && fail {
+				return item, errors.New("nas offline")
+			}
+			if item.Values == nil {
+				item.Values = make(map[string]string)
+			}
+			item.Values[name] = "done"
+			return item, nil
+		})
+	}
+	p := &Pipeline{Stages: []Stage{stage("first"), stage("second"), stage("third")}, StateDir: state}
+
+	_, err := p.Run(context.Background(), item)
+	var stageErr *StageError
+	assert.ErrorAs(t, err, &stageErr)
+	assert.Equal(t, "second", stageErr.Stage)
+
+	fail = false
+	out, err := p.Run(context.Background(), item)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"first": 1, "second": 2, "third": 1}, counts)
+	assert.Equal(t, map[string]string{"first": "done", "second": "done", "third": "done"}, out.Values)
+
+	// a finished item is not run again until it is reset
+	_, err = p.Run(context.Background(), item)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, counts["third"])
+	assert.Nil(t, p.Reset(item))
+	_, err = p.Run(context.Background(), item)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, counts["third"])
+
+	p.Stages = []Stage{stage("other"), stage("second")}
+	_, err = p.Run(context.Background(), item)
+	assert.NotNil(t, err)
+}
+
+func TestRunAll(t *testing.T) {
+	items := []Item{testItem(t, "ONE"), testItem(t, "TWO"), testItem(t, "THREE")}
+	nas := t.TempDir()
+	p := &Pipeline{Stages: []Stage{Move(nas)}, Concurrency: 2}
+
+	outcomes := p.RunAll(context.Background(), items)
+	assert.Equal(t, 3, len(outcomes))
+	for i, o := range outcomes {
+		assert.Nil(t, o.Err)
+		assert.Equal(t, filepath.Join(nas, items[i].Info.Name+"_t00.mkv"), o.Item.Result.Files[0].Path)
+		assert.FileExists(t, o.Item.Result.Files[0].Path)
+	}
+}
+
+func TestItemId(t *testing.T) {
+	dir := t.TempDir()
+	item := func(duration time.Duration, file string) Item {
+		return NewItem(
+			makemkv.DiscInfo{Name: "DVD_VIDEO", Titles: []makemkv.TitleInfo{{Id: 0, Duration: duration}}},
+			makemkv.MkvResult{Destination: dir, Files: []makemkv.OutputFile{{TitleId: 0, Path: filepath.Join(dir, file)}}},
+		)
+	}
+	first := item(time.Hour, "DVD_VIDEO_t00.mkv")
+	assert.Equal(t, first.id(), item(time.Hour, "DVD_VIDEO_t00.mkv").id())
+	// generic disc names in the same destination do not share a state
+	assert.NotEqual(t, first.id(), item(2*time.Hour, "DVD_VIDEO_t00.mkv").id())
+	assert.NotEqual(t, first.id(), item(time.Hour, "DVD_VIDEO_t01.mkv").id())
+
+	first.Id = "movie"
+	assert.Equal(t, "movie", first.id())
+}
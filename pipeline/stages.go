@@ -0,0 +1,281 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/aravance/go-makemkv"
+)
+
+// Verify fails the item when an output does not match the scan.
+func Verify() Stage {
+	return Func("verify", func(ctx context.Context, item Item) (Item, error) {
+		return item, makemkv.VerifyResult(&item.Result, item.Info)
+	})
+}
+
+// Rename moves the outputs to their templated names.
+func Rename(renamer *makemkv.Renamer, meta makemkv.MetadataFunc) Stage {
+	return Func("rename", func(ctx context.Context, item Item) (Item, error) {
+		item.Result.Files = append([]makemkv.OutputFile(nil), item.Result.Files...)
+		return item, renamer.Rename(&item.Result, item.Info, meta)
+	})
+}
+
+// Tag writes the scan and metadata into the outputs.
+func Tag(meta makemkv.MetadataFunc) Stage {
+	return Func("tag", func(ctx context.Context, item Item) (Item, error) {
+		return item, eachOutput(item, func(output *makemkv.OutputFile, title makemkv.TitleInfo) error {
+			return makemkv.WriteTags(*output, title, meta(title))
+		})
+	})
+}
+
+// eachOutput calls fn for every successful output that has a scanned title.
+func eachOutput(item Item, fn func(output *makemkv.OutputFile, title makemkv.TitleInfo) error) error {
+	for i := range item.Result.Files {
+		output := &item.Result.Files[i]
+		if !output.Success || output.TitleId < 0 || output.TitleId >= len(item.Info.Titles) {
+			continue
+		}
+		if err := fn(output, item.Info.Titles[output.TitleId]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommandData is what the templates of a Command are rendered with. File
+// is only set when the command runs per file.
+type CommandData struct {
+	Item
+	File makemkv.OutputFile
+}
+
+var commandFuncs = template.FuncMap{
+	"base": filepath.Base,
+	"dir":  filepath.Dir,
+	"ext":  filepath.Ext,
+	"trimext": func(path string) string {
+		return strings.TrimSuffix(path, filepath.Ext(path))
+	},
+}
+
+// Command runs an external program like HandBrakeCLI or ffmpeg. The
+// arguments are templates rendered with CommandData and passed to the
+// program without a shell.
+type Command struct {
+	StageName string
+	Args      []string
+	// run once for every successful output instead of once for the item
+	PerFile bool
+	// when set for a per file command, the rendered path replaces the path of
+	// the file, for example the transcoded copy
+	Output string
+	// remove the previous file after Output was produced
+	RemoveInput bool
+}
+
+func (c *Command) Name() string {
+	return c.StageName
+}
+
+func (c *Command) Run(ctx context.Context, item Item) (Item, error) {
+	if len(c.Args) == 0 {
+		return item, errors.New("command is empty")
+	}
+	args := make([]*template.Template, len(c.Args))
+	for i, arg := range c.Args {
+		tmpl, err := template.New(c.StageName).Funcs(commandFuncs).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return item, err
+		}
+		args[i] = tmpl
+	}
+	var output *template.Template
+	if c.Output != "" {
+		var err error
+		if output, err = template.New(c.StageName).Funcs(commandFuncs).Option("missingkey=error").Parse(c.Output); err != nil {
+			return item, err
+		}
+	}
+
+	if !c.PerFile {
+		return item, c.exec(ctx, args, CommandData{Item: item})
+	}
+
+	files := append([]makemkv.OutputFile(nil), item.Result.Files...)
+	for i := range files {
+		if !files[i].Success {
+			continue
+		}
+		data := CommandData{Item: item, File: files[i]}
+		if err := c.exec(ctx, args, data); err != nil {
+			return item, err
+		}
+		if output == nil {
+			continue
+		}
+
+		path, err := render(output, data)
+		if err != nil {
+			return item, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return item, fmt.Errorf("command did not produce %s: %w", path, err)
+		}
+		if c.RemoveInput && path != files[i].Path {
+			if err := os.Remove(files[i].Path); err != nil {
+				return item, err
+			}
+		}
+		files[i].Path = path
+		files[i].Name = filepath.Base(path)
+		files[i].Size = info.Size()
+	}
+	item.Result.Files = files
+	return item, nil
+}
+
+func (c *Command) exec(ctx context.Context, tmpls []*template.Template, data CommandData) error {
+	args := make([]string, len(tmpls))
+	for i, tmpl := range tmpls {
+		arg, err := render(tmpl, data)
+		if err != nil {
+			return err
+		}
+		args[i] = arg
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w: %s", args[0], err, lastLine(msg))
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
+
+func render(tmpl *template.Template, data any) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// Move moves the successful outputs into dir, keeping their names, along
+// with the .sha256 sidecars Checksum wrote. It fails rather than replace a
+// file in dir.
+func Move(dir string) Stage {
+	return Func("move", func(ctx context.Context, item Item) (Item, error) {
+		return transfer(ctx, item, dir, makemkv.MoveFile)
+	})
+}
+
+// Copy copies the successful outputs and their sidecars into dir, the item
+// passed on points at the copies. Like Move it never replaces a file.
+func Copy(dir string) Stage {
+	return Func("copy", func(ctx context.Context, item Item) (Item, error) {
+		return transfer(ctx, item, dir, makemkv.CopyFile)
+	})
+}
+
+func transfer(ctx context.Context, item Item, dir string, fn func(src string, dst string) error) (Item, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return item, err
+	}
+	files := append([]makemkv.OutputFile(nil), item.Result.Files...)
+	checksums := make(map[string]string, len(item.Checksums))
+	for path, sum := range item.Checksums {
+		checksums[path] = sum
+	}
+
+	for i := range files {
+		if !files[i].Success {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return item, err
+		}
+		src := files[i].Path
+		dst := filepath.Join(dir, filepath.Base(src))
+		if dst == src {
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			return item, fmt.Errorf("%w: %s", makemkv.ErrDestinationExists, dst)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return item, err
+		}
+		if err := fn(src, dst); err != nil {
+			return item, err
+		}
+		if _, err := os.Stat(src + sidecarExt); err == nil {
+			if err := fn(src+sidecarExt, dst+sidecarExt); err != nil {
+				return item, err
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return item, err
+		}
+		if sum, ok := checksums[src]; ok {
+			checksums[dst] = sum
+		}
+		files[i].Path = dst
+	}
+
+	item.Result.Files = files
+	if len(checksums) > 0 {
+		item.Checksums = checksums
+	}
+	return item, nil
+}
+
+const sidecarExt = ".sha256"
+
+// Checksum computes the sha256 of the successful outputs. With sidecar set
+// it also writes a sha256sum compatible <file>.sha256 next to each of them.
+func Checksum(sidecar bool) Stage {
+	return Func("checksum", func(ctx context.Context, item Item) (Item, error) {
+		checksums := make(map[string]string, len(item.Checksums))
+		for path, sum := range item.Checksums {
+			checksums[path] = sum
+		}
+		for _, output := range item.Result.Files {
+			if !output.Success {
+				continue
+			}
+			sum, err := makemkv.HashFile(ctx, output.Path)
+			if err != nil {
+				return item, err
+			}
+			checksums[output.Path] = sum
+			if sidecar {
+				line := sum + "  " + filepath.Base(output.Path) + "\n"
+				if err := os.WriteFile(output.Path+sidecarExt, []byte(line), 0644); err != nil {
+					return item, err
+				}
+			}
+		}
+		item.Checksums = checksums
+		return item, nil
+	})
+}
@@ -0,0 +1,97 @@
+package makemkv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var errStatfsUnsupported = errors.New("makemkv: free space check not supported on this platform")
+
+type DestinationError struct {
+	Path string
+	Err  error
+}
+
+func (e *DestinationError) Error() string {
+	return "makemkv: invalid destination " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *DestinationError) Unwrap() error {
+	return e.Err
+}
+
+type InsufficientSpaceError struct {
+	Path      string
+	Required  int64
+	Available int64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("makemkv: not enough space in %s: %d bytes required, %d available", e.Path, e.Required, e.Available)
+}
+
+type OverwriteError struct {
+	Files []string
+}
+
+func (e *OverwriteError) Error() string {
+	return "makemkv: rip would overwrite existing files: " + strings.Join(e.Files, ", ")
+}
+
+// Preflight validates the destination before makemkvcon is started. The
+// space and overwrite checks need Info, without it only the destination
+// itself is checked.
+func (j *MkvJob) Preflight() error {
+	info, err := os.Stat(j.destination)
+	if err != nil {
+		return &DestinationError{Path: j.destination, Err: err}
+	}
+	if !info.IsDir() {
+		return &DestinationError{Path: j.destination, Err: errors.New("not a directory")}
+	}
+	probe, err := os.CreateTemp(j.destination, ".makemkv-preflight-*")
+	if err != nil {
+		return &DestinationError{Path: j.destination, Err: err}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if j.Info == nil {
+		return nil
+	}
+
+	var required int64
+	var existing []string
+	for _, t := range j.Info.Titles {
+		if j.titleId != "all" && j.titleId != strconv.Itoa(t.Id) {
+			continue
+		}
+		required += t.FileSize
+		if t.FileName == "" {
+			continue
+		}
+		path := filepath.Join(j.destination, t.FileName)
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	if len(existing) > 0 && !j.Overwrite {
+		return &OverwriteError{Files: existing}
+	}
+
+	required += j.SpaceMargin
+	available, err := freeSpace(j.destination)
+	if errors.Is(err, errStatfsUnsupported) {
+		return nil
+	} else if err != nil {
+		return &DestinationError{Path: j.destination, Err: err}
+	}
+	if available < required {
+		return &InsufficientSpaceError{Path: j.destination, Required: required, Available: available}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package makemkv
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreflightDestination(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, Mkv(NewDiscDevice(0), 0, dir, MkvOptions{}).Preflight())
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 0, len(entries))
+
+	var destErr *DestinationError
+	err := Mkv(NewDiscDevice(0), 0, filepath.Join(dir, "missing"), MkvOptions{}).Preflight()
+	assert.True(t, errors.As(err, &destErr))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	file := filepath.Join(dir, "file")
+	assert.Nil(t, os.WriteFile(file, nil, 0644))
+	err = Mkv(NewDiscDevice(0), 0, file, MkvOptions{}).Preflight()
+	assert.True(t, errors.As(err, &destErr))
+	assert.Equal(t, file, destErr.Path)
+}
+
+func TestPreflightOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	info := &DiscInfo{Titles: []TitleInfo{
+		{Id: 0, FileName: "Movie_t00.mkv", FileSize: 1024},
+		{Id: 1, FileName: "Extras_t01.mkv", FileSize: 1024},
+	}}
+	existing := filepath.Join(dir, "Extras_t01.mkv")
+	assert.Nil(t, os.WriteFile(existing, []byte("extras"), 0644))
+
+	job := Mkv(NewDiscDevice(0), 0, dir, MkvOptions{})
+	job.Info = info
+	assert.Nil(t, job.Preflight())
+
+	job = MkvAll(NewDiscDevice(0), 0, dir, MkvOptions{})
+	job.Info = info
+	var overwriteErr *OverwriteError
+	assert.True(t, errors.As(job.Preflight(), &overwriteErr))
+	assert.Equal(t, []string{existing}, overwriteErr.Files)
+
+	job.Overwrite = true
+	assert.Nil(t, job.Preflight())
+}
+
+func TestPreflightSpace(t *testing.T) {
+	dir := t.TempDir()
+	available, err := freeSpace(dir)
+	if errors.Is(err, errStatfsUnsupported) {
+		t.Skip(err)
+	}
+	assert.Nil(t, err)
+
+	job := Mkv(NewDiscDevice(0), 0, dir, MkvOptions{})
+	job.Info = &DiscInfo{Titles: []TitleInfo{{Id: 0, FileName: "Movie_t00.mkv", FileSize: available}}}
+	job.SpaceMargin = 1 << 40
+	var spaceErr *InsufficientSpaceError
+	assert.True(t, errors.As(job.Preflight(), &spaceErr))
+	assert.Equal(t, dir, spaceErr.Path)
+	assert.Equal(t, available+1<<40, spaceErr.Required)
+	assert.Greater(t, spaceErr.Available, int64(0))
+
+	job.Info.Titles[0].FileSize = 1024
+	job.SpaceMargin = 0
+	assert.Nil(t, job.Preflight())
+}
@@ -0,0 +1,485 @@
+package makemkv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrJobNotFound = errors.New("makemkv: job not found")
+
+type JobKind string
+
+const (
+	JobInfo   JobKind = "info"
+	JobMkv    JobKind = "mkv"
+	JobBackup JobKind = "backup"
+)
+
+type JobState string
+
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobDone      JobState = "done"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+	JobSkipped   JobState = "skipped"
+)
+
+func (s JobState) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled || s == JobSkipped
+}
+
+// QueueJob describes a job by value so the queue can be written to disk and
+// picked up again after a restart.
+type QueueJob struct {
+	Id          string
+	Kind        JobKind
+	Device      string
+	TitleId     string
+	Angle       int
+	Destination string
+	Options     MkvOptions
+	Priority    int
+
+	State    JobState
+	Error    string
+	Class    ErrorClass `json:",omitempty"`
+	Created  time.Time
+	Started  time.Time
+	Finished time.Time
+
+	Info   *DiscInfo     `json:",omitempty"`
+	Result *MkvResult    `json:",omitempty"`
+	Backup *BackupResult `json:",omitempty"`
+}
+
+type Queue struct {
+	// called from the worker goroutine every time a job reaches a final
+	// state, the next job on the drive may already be running
+	OnDone func(job QueueJob)
+	// attached to every job the queue runs
+	Observer Observer
+	// the makemkvcon binary every job runs, see MkvOptions.Executable
+	Executable string
+	// called for every progress value and message of a running mkv or
+	// backup job
+	OnStatus  func(job QueueJob, status Status)
+	OnMessage func(job QueueJob, msg Message)
+	// called before a job runs, a job it returns true for is marked skipped.
+	// It may fill in job.Info, which is kept for the job.
+	Skip func(ctx context.Context, job *QueueJob) (bool, error)
+
+	path        string
+	concurrency int
+
+	mu        sync.Mutex
+	jobs      []*QueueJob
+	paused    bool
+	running   map[string]context.CancelFunc
+	cancelled map[string]bool
+	busy      map[string]bool
+	wake      chan struct{}
+}
+
+// NewQueue loads the queue state from path, if it exists. Jobs that were
+// running when the state was written are put back in the pending state.
+// An empty path keeps the queue in memory only.
+func NewQueue(path string, concurrency int) (*Queue, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	q := &Queue{
+		path:        path,
+		concurrency: concurrency,
+		running:     make(map[string]context.CancelFunc),
+		cancelled:   make(map[string]bool),
+		busy:        make(map[string]bool),
+		wake:        make(chan struct{}, 1),
+	}
+	if path == "" {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.jobs); err != nil {
+		return nil, fmt.Errorf("makemkv: reading queue state: %w", err)
+	}
+	for _, job := range q.jobs {
+		if job.State == JobRunning {
+			job.State = JobPending
+			job.Started = time.Time{}
+		}
+	}
+	return q, nil
+}
+
+func (q *Queue) Add(job QueueJob) (string, error) {
+	device, err := ParseDevice(job.Device)
+	if err != nil {
+		return "", err
+	}
+	job.Device = DeviceString(device)
+	switch job.Kind {
+	case JobInfo:
+	case JobMkv, JobBackup:
+		if job.Destination == "" {
+			return "", fmt.Errorf("makemkv: %s job needs a destination", job.Kind)
+		}
+	default:
+		return "", fmt.Errorf("makemkv: unknown job kind %q", job.Kind)
+	}
+
+	job.Id = newJobId()
+	job.State = JobPending
+	job.Error = ""
+	job.Class = ClassNone
+	job.Created = time.Now()
+
+	q.mu.Lock()
+	q.jobs = append(q.jobs, &job)
+	err = q.save()
+	q.mu.Unlock()
+
+	q.notify()
+	return job.Id, err
+}
+
+func (q *Queue) Job(id string) (QueueJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job := q.find(id); job != nil {
+		return *job, nil
+	}
+	return QueueJob{}, ErrJobNotFound
+}
+
+func (q *Queue) Jobs() []QueueJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]QueueJob, len(q.jobs))
+	for i, job := range q.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// Cancel removes a pending job from the schedule or stops a running one.
+func (q *Queue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := q.find(id)
+	if job == nil {
+		return ErrJobNotFound
+	}
+	switch job.State {
+	case JobPending:
+		job.State = JobCancelled
+		job.Class = ClassCancelled
+		job.Finished = time.Now()
+		return q.save()
+	case JobRunning:
+		q.cancelled[id] = true
+		q.running[id]()
+	}
+	return nil
+}
+
+func (q *Queue) SetPriority(id string, priority int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.find(id)
+	if job == nil {
+		return ErrJobNotFound
+	}
+	job.Priority = priority
+	return q.save()
+}
+
+// Pause stops new jobs from being started, running jobs are not affected.
+func (q *Queue) Pause() {
+	q.mu.Lock()
+	q.paused = true
+	q.mu.Unlock()
+}
+
+func (q *Queue) Resume() {
+	q.mu.Lock()
+	q.paused = false
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *Queue) Paused() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.paused
+}
+
+// Run schedules jobs until ctx is cancelled. Each device runs one job at a
+// time, different devices run in parallel up to the concurrency limit.
+// Running jobs are cancelled and waited for before Run returns.
+func (q *Queue) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		q.mu.Lock()
+		for _, job := range q.next() {
+			jobCtx, cancel := context.WithCancel(ctx)
+			job.State = JobRunning
+			job.Started = time.Now()
+			q.running[job.Id] = cancel
+			q.busy[deviceKey(job.Device)] = true
+
+			wg.Add(1)
+			go func(job *QueueJob) {
+				defer wg.Done()
+				defer cancel()
+				q.execute(jobCtx, job)
+			}(job)
+		}
+		err := q.save()
+		q.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.wake:
+		}
+	}
+}
+
+// next picks the jobs that can start now, highest priority first, then in
+// order of arrival. Must be called with q.mu held.
+func (q *Queue) next() []*QueueJob {
+	if q.paused {
+		return nil
+	}
+
+	var pending []*QueueJob
+	for _, job := range q.jobs {
+		if job.State == JobPending {
+			pending = append(pending, job)
+		}
+	}
+	sort.SliceStable(pending, func(a, b int) bool {
+		if pending[a].Priority != pending[b].Priority {
+			return pending[a].Priority > pending[b].Priority
+		}
+		return pending[a].Created.Before(pending[b].Created)
+	})
+
+	var jobs []*QueueJob
+	busy := make(map[string]bool)
+	for _, job := range pending {
+		if len(q.running)+len(jobs) >= q.concurrency {
+			break
+		}
+		key := deviceKey(job.Device)
+		if q.busy[key] || busy[key] {
+			continue
+		}
+		busy[key] = true
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func (q *Queue) execute(ctx context.Context, job *QueueJob) {
+	q.mu.Lock()
+	spec := *job
+	q.mu.Unlock()
+	spec.Options.Executable = q.Executable
+
+	var skipped bool
+	var info *DiscInfo
+	var result *MkvResult
+	var backup *BackupResult
+	var err error
+	if q.Skip != nil {
+		skipped, err = q.Skip(ctx, &spec)
+		info = spec.Info
+	}
+	if err == nil && !skipped {
+		statuses, messages, wait := q.forward(spec)
+		info, result, backup, err = runQueueJob(ctx, spec, q.Observer, statuses, messages)
+		wait()
+	}
+
+	q.mu.Lock()
+	job.Info = info
+	job.Result = result
+	job.Backup = backup
+	job.Finished = time.Now()
+	switch {
+	case skipped:
+		job.State = JobSkipped
+	case q.cancelled[job.Id]:
+		job.State = JobCancelled
+		job.Class = ClassCancelled
+	case ctx.Err() != nil:
+		// the queue is shutting down, run the job again on the next start
+		job.State = JobPending
+		job.Started = time.Time{}
+		job.Finished = time.Time{}
+	case err != nil:
+		job.State = JobFailed
+		job.Error = err.Error()
+		job.Class = Classify(err)
+	default:
+		job.State = JobDone
+	}
+	delete(q.running, job.Id)
+	delete(q.cancelled, job.Id)
+	delete(q.busy, deviceKey(job.Device))
+	q.save()
+	done := *job
+	q.mu.Unlock()
+
+	// the drive is free, start the next job before OnDone, which may take a
+	// while
+	q.notify()
+	if q.OnDone != nil && done.State.Finished() {
+		q.OnDone(done)
+	}
+}
+
+// forward hands the progress and messages of job to OnStatus and OnMessage.
+// wait closes the channels once the job has returned and waits for the last
+// value to be handled.
+func (q *Queue) forward(job QueueJob) (chan Status, chan Message, func()) {
+	if q.OnStatus == nil && q.OnMessage == nil {
+		return nil, nil, func() {}
+	}
+	statuses := make(chan Status)
+	messages := make(chan Message)
+	done := make(chan struct{})
+	go func(statuses chan Status, messages chan Message) {
+		defer close(done)
+		for statuses != nil || messages != nil {
+			select {
+			case status, ok := <-statuses:
+				if !ok {
+					statuses = nil
+				} else if q.OnStatus != nil {
+					q.OnStatus(job, status)
+				}
+			case msg, ok := <-messages:
+				if !ok {
+					messages = nil
+				} else if q.OnMessage != nil {
+					q.OnMessage(job, msg)
+				}
+			}
+		}
+	}(statuses, messages)
+	return statuses, messages, func() {
+		close(statuses)
+		close(messages)
+		<-done
+	}
+}
+
+func runQueueJob(ctx context.Context, job QueueJob, observer Observer, statuses chan Status, messages chan Message) (*DiscInfo, *MkvResult, *BackupResult, error) {
+	device, err := ParseDevice(job.Device)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	switch job.Kind {
+	case JobInfo:
+		scan := Info(device, job.Options)
+		scan.Observer = observer
+		info, err := scan.RunContext(ctx)
+		return info, nil, nil, err
+	case JobMkv:
+		var mkv *MkvJob
+		if job.TitleId == "" || job.TitleId == "all" {
+			mkv = MkvAll(device, 0, job.Destination, job.Options)
+		} else if id, err := strconv.Atoi(job.TitleId); err != nil || id < 0 {
+			return nil, nil, nil, fmt.Errorf("makemkv: invalid title id %q", job.TitleId)
+		} else {
+			mkv = Mkv(device, id, job.Destination, job.Options)
+		}
+		mkv.Info = job.Info
+		mkv.Angle = job.Angle
+		mkv.Statuschan = statuses
+		mkv.Messagechan = messages
+		mkv.Observer = observer
+		result, err := mkv.RunContext(ctx)
+		return job.Info, result, nil, err
+	case JobBackup:
+		backup := Backup(device, job.Destination, job.Options)
+		backup.Statuschan = statuses
+		backup.Messagechan = messages
+		backup.Observer = observer
+		result, err := backup.RunContext(ctx)
+		return nil, nil, result, err
+	default:
+		return nil, nil, nil, fmt.Errorf("makemkv: unknown job kind %q", job.Kind)
+	}
+}
+
+// deviceKey is the source as makemkvcon sees it, so "dev:sr0" and
+// "dev:/dev/sr0" share a drive. Jobs from an older state file may hold a
+// device that does not parse, those are kept apart by their text.
+func deviceKey(device string) string {
+	if d, err := ParseDevice(device); err == nil {
+		return DeviceString(d)
+	}
+	return device
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) find(id string) *QueueJob {
+	for _, job := range q.jobs {
+		if job.Id == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// save writes the queue state to disk. Must be called with q.mu held.
+func (q *Queue) save() error {
+	if q.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(q.jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(q.path, data)
+}
+
+func newJobId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
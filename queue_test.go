@@ -0,0 +1,175 @@
+package makemkv
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueNext(t *testing.T) {
+	q, err := NewQueue("", 2)
+	assert.Nil(t, err)
+
+	q.Add(QueueJob{Kind: JobInfo, Device: "disc:0"})
+	high, _ := q.Add(QueueJob{Kind: JobInfo, Device: "disc:0", Priority: 10})
+	other, _ := q.Add(QueueJob{Kind: JobBackup, Device: "disc:1", Destination: "/tmp"})
+	third, _ := q.Add(QueueJob{Kind: JobInfo, Device: "disc:2"})
+
+	next := q.next()
+	assert.Equal(t, 2, len(next))
+	assert.Equal(t, high, next[0].Id)
+	assert.Equal(t, other, next[1].Id)
+
+	q.running[high] = func() {}
+	q.busy["disc:0"] = true
+	next = q.next()
+	assert.Equal(t, 1, len(next))
+	assert.Equal(t, other, next[0].Id)
+
+	q.Pause()
+	assert.Empty(t, q.next())
+	q.Resume()
+
+	assert.Nil(t, q.Cancel(third))
+	job, _ := q.Job(third)
+	assert.Equal(t, JobCancelled, job.State)
+	assert.Equal(t, ErrJobNotFound, q.Cancel("missing"))
+
+	_, err = q.Add(QueueJob{Kind: JobMkv, Device: "disc:0"})
+	assert.NotNil(t, err)
+	_, err = q.Add(QueueJob{Kind: JobInfo, Device: "floppy:0"})
+	assert.NotNil(t, err)
+}
+
+func TestQueueSameDrive(t *testing.T) {
+	q, err := NewQueue("", 2)
+	assert.Nil(t, err)
+
+	first, _ := q.Add(QueueJob{Kind: JobInfo, Device: "dev:sr0"})
+	q.Add(QueueJob{Kind: JobInfo, Device: "dev:/dev/sr0"})
+	job, _ := q.Job(first)
+	assert.Equal(t, "dev:/dev/sr0", job.Device)
+
+	next := q.next()
+	assert.Equal(t, 1, len(next))
+	assert.Equal(t, first, next[0].Id)
+
+	// a device from an older state file that no longer parses
+	q.jobs = append(q.jobs, &QueueJob{Id: "old", Kind: JobInfo, Device: "sr1", State: JobPending})
+	q.busy["dev:/dev/sr0"] = true
+	next = q.next()
+	assert.Equal(t, 1, len(next))
+	assert.Equal(t, "old", next[0].Id)
+
+	_, err = q.Add(QueueJob{Kind: JobInfo, Device: "disc:sr0"})
+	assert.NotNil(t, err)
+	_, err = q.Add(QueueJob{Kind: JobInfo, Device: ""})
+	assert.NotNil(t, err)
+}
+
+func TestQueuePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q, err := NewQueue(path, 1)
+	assert.Nil(t, err)
+	id, err := q.Add(QueueJob{Kind: JobMkv, Device: "dev:/dev/sr0", TitleId: "3", Destination: "/tmp", Options: MkvOptions{Cache: Intopt(512)}})
+	assert.Nil(t, err)
+
+	q.mu.Lock()
+	q.find(id).State = JobRunning
+	q.find(id).Started = time.Now()
+	q.save()
+	q.mu.Unlock()
+
+	restored, err := NewQueue(path, 1)
+	assert.Nil(t, err)
+	job, err := restored.Job(id)
+	assert.Nil(t, err)
+	assert.Equal(t, JobPending, job.State)
+	assert.Equal(t, "3", job.TitleId)
+	assert.Equal(t, 512, *job.Options.Cache)
+}
+
+func TestQueueSkip(t *testing.T) {
+	q, err := NewQueue("", 1)
+	assert.Nil(t, err)
+	var done QueueJob
+	q.OnDone = func(job QueueJob) { done = job }
+	q.Skip = func(ctx context.Context, job *QueueJob) (bool, error) {
+		job.Info = &DiscInfo{Name: "MOVIE"}
+		return true, nil
+	}
+
+	id, _ := q.Add(QueueJob{Kind: JobMkv, Device: "disc:0", Destination: "/tmp"})
+	q.mu.Lock()
+	job := q.find(id)
+	q.mu.Unlock()
+	q.execute(context.Background(), job)
+
+	assert.Equal(t, id, done.Id)
+	assert.Equal(t, JobSkipped, done.State)
+	assert.True(t, done.State.Finished())
+	assert.Equal(t, "MOVIE", done.Info.Name)
+}
+
+func TestQueueClass(t *testing.T) {
+	q, err := NewQueue("", 1)
+	assert.Nil(t, err)
+	q.Skip = func(ctx context.Context, job *QueueJob) (bool, error) {
+		return false, &JobError{Class: ClassSource, Err: errors.New("exit status 1")}
+	}
+
+	id, _ := q.Add(QueueJob{Kind: JobMkv, Device: "disc:0", Destination: "/tmp"})
+	q.mu.Lock()
+	job := q.find(id)
+	q.mu.Unlock()
+	q.execute(context.Background(), job)
+	failed, _ := q.Job(id)
+	assert.Equal(t, JobFailed, failed.State)
+	assert.Equal(t, ClassSource, failed.Class)
+
+	id, _ = q.Add(QueueJob{Kind: JobInfo, Device: "disc:1"})
+	assert.Nil(t, q.Cancel(id))
+	cancelled, _ := q.Job(id)
+	assert.Equal(t, ClassCancelled, cancelled.Class)
+}
+
+func TestQueueOnDoneBlocking(t *testing.T) {
+	q, err := NewQueue("", 1)
+	assert.Nil(t, err)
+	q.Skip = func(ctx context.Context, job *QueueJob) (bool, error) {
+		return true, nil
+	}
+	first, _ := q.Add(QueueJob{Kind: JobMkv, Device: "disc:0", Destination: "/tmp"})
+	second, _ := q.Add(QueueJob{Kind: JobMkv, Device: "disc:0", Destination: "/tmp"})
+
+	// a slow OnDone of the first job must not hold up the next one
+	release := make(chan struct{})
+	done := make(chan string, 1)
+	q.OnDone = func(job QueueJob) {
+		if job.Id == first {
+			<-release
+		} else {
+			done <- job.Id
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case id := <-done:
+		assert.Equal(t, second, id)
+	case <-time.After(5 * time.Second):
+		t.Error("second job did not finish")
+	}
+	close(release)
+	cancel()
+	<-stopped
+}
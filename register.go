@@ -0,0 +1,165 @@
+package makemkv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidKeyFormat = errors.New("makemkv: invalid registration key format")
+	ErrKeyIncorrect     = errors.New("makemkv: registration key is incorrect")
+	ErrKeyNotSaved      = errors.New("makemkv: registration key could not be saved")
+	ErrKeyExpired       = errors.New("makemkv: registration key or beta period has expired")
+)
+
+// T- for the public beta key, M- for a purchased one
+var keyRegex = regexp.MustCompile(`^[A-Z]-[0-9A-Za-z@_]{40,}$`)
+
+type RegisterStatus int
+
+const (
+	RegisterUnknown RegisterStatus = iota
+	RegisterSaved
+	RegisterNotSaved
+	RegisterIncorrect
+)
+
+func (s RegisterStatus) String() string {
+	switch s {
+	case RegisterSaved:
+		return "saved"
+	case RegisterNotSaved:
+		return "not saved"
+	case RegisterIncorrect:
+		return "incorrect"
+	default:
+		return "unknown"
+	}
+}
+
+type RegisterResult struct {
+	Status   RegisterStatus
+	Messages []Message
+}
+
+func (r RegisterResult) Err() error {
+	switch r.Status {
+	case RegisterSaved:
+		return nil
+	case RegisterIncorrect:
+		return ErrKeyIncorrect
+	default:
+		return ErrKeyNotSaved
+	}
+}
+
+func ValidateKey(key string) error {
+	if !keyRegex.MatchString(strings.TrimSpace(key)) {
+		return ErrInvalidKeyFormat
+	}
+	return nil
+}
+
+func Register(ctx context.Context, key string, opts MkvOptions) (*RegisterResult, error) {
+	key = strings.TrimSpace(key)
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, opts.executable(), "-r", "reg", key)
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result := parseRegister(bufio.NewScanner(bytes.NewReader(out)))
+	if result.Status == RegisterUnknown && err != nil {
+		return nil, err
+	}
+	return &result, result.Err()
+}
+
+func parseRegister(scanner *bufio.Scanner) RegisterResult {
+	var result RegisterResult
+	for scanner.Scan() {
+		prefix, content, found := strings.Cut(scanner.Text(), ":")
+		if !found || prefix != "MSG" {
+			continue
+		}
+		msg, ok := parseMessage(content)
+		if !ok {
+			continue
+		}
+		result.Messages = append(result.Messages, msg)
+
+		switch msg.Code {
+		case msgAppIfaceRegisterCodeSaved:
+			result.Status = RegisterSaved
+		case msgAppIfaceRegisterCodeNotSaved:
+			result.Status = RegisterNotSaved
+		case msgAppIfaceRegisterCodeIncorrect:
+			result.Status = RegisterIncorrect
+		}
+	}
+	return result
+}
+
+// WriteKey stores the key in settings.conf directly, bypassing makemkvcon.
+// An empty path means the default settings location.
+func WriteKey(path string, key string) error {
+	key = strings.TrimSpace(key)
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if path == "" {
+		var err error
+		if path, err = DefaultSettingsPath(); err != nil {
+			return err
+		}
+	}
+	settings, err := LoadSettings(path)
+	if err != nil {
+		return err
+	}
+	settings.Set(SettingKey, key)
+	return settings.Save()
+}
+
+type KeyStatus struct {
+	Installed  bool
+	Valid      bool
+	Key        string
+	KeyType    string
+	Expiration time.Time
+}
+
+// CheckKey reports the key configured in settings.conf together with what
+// makemkvcon thinks of it.
+func CheckKey(ctx context.Context, opts MkvOptions) (*KeyStatus, error) {
+	path, err := DefaultSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+	settings, err := LoadSettings(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var status KeyStatus
+	status.Key, status.Installed = settings.Get(SettingKey)
+
+	license, err := License(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	status.KeyType = license.KeyType
+	status.Expiration = license.KeyExpiration
+	status.Valid = status.Installed && ValidateKey(status.Key) == nil && !license.Expired()
+	return &status, nil
+}
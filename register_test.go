@@ -0,0 +1,41 @@
+package makemkv
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateKey(t *testing.T) {
+	valid := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUV0123456789@_ab"
+	assert.Nil(t, ValidateKey("T-"+valid))
+	assert.Nil(t, ValidateKey("M-"+valid))
+	assert.Equal(t, ErrInvalidKeyFormat, ValidateKey(""))
+	assert.Equal(t, ErrInvalidKeyFormat, ValidateKey("T-short"))
+	assert.Equal(t, ErrInvalidKeyFormat, ValidateKey(valid))
+	assert.Equal(t, ErrInvalidKeyFormat, ValidateKey("t-"+valid))
+	assert.Equal(t, ErrInvalidKeyFormat, ValidateKey("T-"+valid+"!"))
+	assert.Equal(t, ErrInvalidKeyFormat, ValidateKey("T-"+valid[:20]+" "+valid[20:]))
+}
+
+func TestParseRegister(t *testing.T) {
+	banner := `MSG:1005,0,1,"MakeMKV v1.17.6 linux(x64-release) started","%1 started","MakeMKV v1.17.6 linux(x64-release)"` + "\n"
+	tests := []struct {
+		input  string
+		status RegisterStatus
+		err    error
+	}{
+		{`MSG:6079,0,0,"Registration key saved","Registration key saved"`, RegisterSaved, nil},
+		{`MSG:6078,0,0,"Registration key not saved","Registration key not saved"`, RegisterNotSaved, ErrKeyNotSaved},
+		{`MSG:6077,0,0,"Registration key is incorrect","Registration key is incorrect"`, RegisterIncorrect, ErrKeyIncorrect},
+		{``, RegisterUnknown, ErrKeyNotSaved},
+	}
+	for _, test := range tests {
+		result := parseRegister(bufio.NewScanner(strings.NewReader(banner + test.input)))
+		assert.Equal(t, test.status, result.Status, test.input)
+		assert.Equal(t, test.err, result.Err(), test.input)
+		assert.Equal(t, msgAppStarted, result.Messages[0].Code)
+	}
+}
@@ -0,0 +1,125 @@
+package makemkv
+
+import (
+	"context"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// applied in order before every attempt after the first
+	Escalations []Escalation
+	// error classes worth another attempt, ClassTransient when empty
+	RetryOn []ErrorClass
+}
+
+// Escalation adjusts the options for the given attempt (starting at 2). The
+// returned undo function, if any, is called once the job has finished.
+type Escalation func(attempt int, opts *MkvOptions) (undo func(), err error)
+
+type Attempt struct {
+	Number   int
+	Options  MkvOptions
+	Messages []Message
+	Error    string
+	Class    ErrorClass
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	class := Classify(err)
+	if len(p.RetryOn) == 0 {
+		return class == ClassTransient
+	}
+	for _, c := range p.RetryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 2; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return d
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// RaiseCache grows --cache by step megabytes on every retry. A job without
+// --cache is left alone, makemkvcon then sizes the cache for the disc itself
+// and any fixed size could be smaller than that.
+func RaiseCache(step int) Escalation {
+	return func(attempt int, opts *MkvOptions) (func(), error) {
+		if opts.Cache != nil {
+			opts.Cache = Intopt(*opts.Cache + step)
+		}
+		return nil, nil
+	}
+}
+
+func ToggleDirectio() Escalation {
+	return func(attempt int, opts *MkvOptions) (func(), error) {
+		directio := true
+		if opts.Directio != nil {
+			directio = !*opts.Directio
+		}
+		opts.Directio = Boolopt(directio)
+		return nil, nil
+	}
+}
+
+// IgnoreReadErrors turns on io_IgnoreReadErrors in settings.conf from the
+// given attempt on, restoring the previous value when the job is done. An
+// empty path means the default settings location.
+func IgnoreReadErrors(from int, path string) Escalation {
+	return func(attempt int, opts *MkvOptions) (func(), error) {
+		if attempt != from {
+			return nil, nil
+		}
+		if path == "" {
+			var err error
+			if path, err = DefaultSettingsPath(); err != nil {
+				return nil, err
+			}
+		}
+		settings, err := LoadSettings(path)
+		if err != nil {
+			return nil, err
+		}
+		previous, existed := settings.Get(SettingIgnoreReadErrors)
+		settings.Set(SettingIgnoreReadErrors, "true")
+		if err := settings.Save(); err != nil {
+			return nil, err
+		}
+		return func() {
+			if settings, err := LoadSettings(path); err == nil {
+				if existed {
+					settings.Set(SettingIgnoreReadErrors, previous)
+				} else {
+					settings.Delete(SettingIgnoreReadErrors)
+				}
+				settings.Save()
+			}
+		}, nil
+	}
+}
@@ -0,0 +1,102 @@
+package makemkv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	readError := Message{Code: msgReadError, Flags: ap_UIMSG_BOXERROR, Text: "read error"}
+	expired := Message{Code: msgProtDemoKeyExpired, Text: "too old"}
+	exit := &exec.ExitError{}
+	tests := []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{nil, ClassNone},
+		{context.Canceled, ClassCancelled},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), ClassCancelled},
+		{newJobError(exit, []Message{readError}), ClassTransient},
+		{newJobError(exit, []Message{readError, expired}), ClassLicense},
+		{newJobError(exit, []Message{{Code: msgOpenDiscFailed}}), ClassSource},
+		{newJobError(exit, []Message{{Code: msgSaveMkvFreeSpace}}), ClassDestination},
+		{newJobError(exit, nil), ClassUnknown},
+		{&DestinationError{Path: "/out", Err: os.ErrNotExist}, ClassDestination},
+		{&InsufficientSpaceError{Path: "/out"}, ClassDestination},
+		{&OverwriteError{Files: []string{"/out/a.mkv"}}, ClassDestination},
+		{ErrKeyIncorrect, ClassLicense},
+		{ErrKeyExpired, ClassLicense},
+		{errors.New("other"), ClassUnknown},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Classify(test.err), fmt.Sprint(test.err))
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	transient := newJobError(&exec.ExitError{}, []Message{{Code: msgReadError}})
+	source := newJobError(&exec.ExitError{}, []Message{{Code: msgOpenDiscFailed}})
+
+	p := &RetryPolicy{}
+	assert.True(t, p.retryable(transient))
+	assert.False(t, p.retryable(source))
+	assert.False(t, p.retryable(context.Canceled))
+
+	p.RetryOn = []ErrorClass{ClassSource, ClassUnknown}
+	assert.False(t, p.retryable(transient))
+	assert.True(t, p.retryable(source))
+	assert.True(t, p.retryable(errors.New("other")))
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := &RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(2))
+	assert.Equal(t, 2*time.Second, p.delay(3))
+	assert.Equal(t, 4*time.Second, p.delay(4))
+	assert.Equal(t, 5*time.Second, p.delay(5))
+	assert.Equal(t, 5*time.Second, p.delay(10))
+
+	p.MaxBackoff = 0
+	assert.Equal(t, 8*time.Second, p.delay(5))
+}
+
+func TestRaiseCache(t *testing.T) {
+	opts := MkvOptions{}
+	RaiseCache(256)(2, &opts)
+	assert.Nil(t, opts.Cache)
+
+	opts.Cache = Intopt(1024)
+	RaiseCache(256)(2, &opts)
+	RaiseCache(256)(3, &opts)
+	assert.Equal(t, 1536, *opts.Cache)
+}
+
+const readErrorMakemkvcon = `#!/bin/sh
+echo 'MSG:2003,516,3,"Error reading sector","Error reading sector"'
+exit 1
+`
+
+func TestRetryCancelledBackoff(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "makemkvcon")
+	assert.Nil(t, os.WriteFile(bin, []byte(readErrorMakemkvcon), 0755))
+
+	job := Mkv(NewDiscDevice(0), 0, t.TempDir(), MkvOptions{Executable: bin})
+	job.Retry = &RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	result, err := job.RunContext(ctx)
+	assert.Equal(t, ClassCancelled, Classify(err))
+	assert.NotNil(t, result)
+	assert.Equal(t, 1, len(result.Attempts))
+	assert.Equal(t, ClassTransient, result.Attempts[0].Class)
+	assert.Equal(t, msgReadError, result.Messages[0].Code)
+}
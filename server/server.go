@@ -0,0 +1,424 @@
+// Package server exposes makemkv drives and jobs over an HTTP/JSON API.
+//
+//	GET    /drives           list drives
+//	GET    /jobs             list jobs
+//	POST   /jobs             start a job, the body is a JobRequest
+//	GET    /jobs/{id}        job status
+//	DELETE /jobs/{id}        cancel a running job
+//	GET    /jobs/{id}/events Server-Sent Events with progress and messages
+//
+// Every request needs the static token, either as "Authorization: Bearer
+// <token>" or as a token query parameter for EventSource clients.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aravance/go-makemkv"
+)
+
+var ErrDeviceBusy = errors.New("server: device is busy")
+
+// buffered events per subscriber, slow clients miss events past this and
+// should fetch the job instead
+const subscriberBuffer = 256
+
+type Event struct {
+	// "status", "message" or "done"
+	Type    string
+	Status  *makemkv.Status   `json:",omitempty"`
+	Message *makemkv.Message  `json:",omitempty"`
+	Job     *makemkv.QueueJob `json:",omitempty"`
+}
+
+// JobRequest is the body of POST /jobs. It carries only what a client may
+// choose, the output files of makemkvcon and the disc info stay with the
+// server.
+type JobRequest struct {
+	Kind        makemkv.JobKind
+	Device      string
+	TitleId     string
+	Angle       int
+	Destination string
+	Priority    int
+	Options     struct {
+		Directio  *bool
+		Cache     *int
+		Minlength *int
+		Noscan    bool
+		Decrypt   bool
+	}
+}
+
+func (r JobRequest) queueJob() makemkv.QueueJob {
+	return makemkv.QueueJob{
+		Kind:        r.Kind,
+		Device:      r.Device,
+		TitleId:     r.TitleId,
+		Angle:       r.Angle,
+		Destination: r.Destination,
+		Priority:    r.Priority,
+		Options: makemkv.MkvOptions{
+			Directio:  r.Options.Directio,
+			Cache:     r.Options.Cache,
+			Minlength: r.Options.Minlength,
+			Noscan:    r.Options.Noscan,
+			Decrypt:   r.Options.Decrypt,
+		},
+	}
+}
+
+type Server struct {
+	// attached to every job the server runs
+	Observer makemkv.Observer
+	// the makemkvcon binary for the drive list and every job, see
+	// makemkv.MkvOptions.Executable
+	Executable string
+
+	token string
+	queue *makemkv.Queue
+
+	ctx  context.Context
+	stop context.CancelFunc
+	once sync.Once
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// job holds the events of a job for its subscribers, the job itself lives in
+// the queue.
+type job struct {
+	status      *makemkv.Status
+	messages    []makemkv.Message
+	subscribers map[chan Event]bool
+	// the final job once the done event was sent
+	done *makemkv.QueueJob
+}
+
+// New creates a server that only accepts requests carrying token. An empty
+// token rejects every request.
+func New(token string) *Server {
+	ctx, stop := context.WithCancel(context.Background())
+	// an in-memory queue never fails to load. Start refuses a busy device,
+	// so with no limit on drives every job starts as soon as it is added.
+	queue, _ := makemkv.NewQueue("", math.MaxInt)
+	s := &Server{
+		token: token,
+		queue: queue,
+		ctx:   ctx,
+		stop:  stop,
+		jobs:  make(map[string]*job),
+	}
+	queue.OnStatus = s.onStatus
+	queue.OnMessage = s.onMessage
+	queue.OnDone = s.onDone
+	return s
+}
+
+// Close cancels all running jobs and waits for them to finish.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.stop()
+	s.mu.Unlock()
+	s.wg.Wait()
+	// the queue keeps unfinished jobs for a restart, which the server does
+	// not have
+	for _, spec := range s.queue.Jobs() {
+		if !spec.State.Finished() {
+			spec.State = makemkv.JobCancelled
+		}
+		s.onDone(spec)
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "drives":
+		s.handleDrives(w, r)
+	case path == "jobs":
+		s.handleJobs(w, r)
+	case len(parts) == 2 && parts[0] == "jobs":
+		s.handleJob(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "events":
+		s.handleEvents(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return false
+	}
+	token := r.URL.Query().Get("token")
+	if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = auth
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) handleDrives(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	drives, err := makemkv.ListDrives(r.Context(), makemkv.MkvOptions{Executable: s.Executable})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJson(w, http.StatusOK, drives)
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, s.Jobs())
+	case http.MethodPost:
+		var req JobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		job, err := s.Start(req.queueJob())
+		switch {
+		case errors.Is(err, ErrDeviceBusy):
+			writeError(w, http.StatusConflict, err)
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+		default:
+			writeJson(w, http.StatusCreated, job)
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request, id string) {
+	var job makemkv.QueueJob
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		job, err = s.Job(id)
+	case http.MethodDelete:
+		err = s.Cancel(id)
+		if err == nil {
+			job, err = s.Job(id)
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if errors.Is(err, makemkv.ErrJobNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJson(w, http.StatusOK, job)
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	events, cancel, err := s.Subscribe(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			flusher.Flush()
+			if e.Type == "done" {
+				return
+			}
+		}
+	}
+}
+
+// Start validates spec and runs it right away. Only one job may run on a
+// device at a time.
+func (s *Server) Start(spec makemkv.QueueJob) (makemkv.QueueJob, error) {
+	device, err := makemkv.ParseDevice(spec.Device)
+	if err != nil {
+		return makemkv.QueueJob{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return makemkv.QueueJob{}, errors.New("server: closed")
+	}
+	for _, j := range s.queue.Jobs() {
+		if j.Device == makemkv.DeviceString(device) && !j.State.Finished() {
+			return makemkv.QueueJob{}, ErrDeviceBusy
+		}
+	}
+
+	s.once.Do(func() {
+		s.queue.Observer = s.Observer
+		s.queue.Executable = s.Executable
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.queue.Run(s.ctx)
+		}()
+	})
+	id, err := s.queue.Add(spec)
+	if err != nil {
+		return makemkv.QueueJob{}, err
+	}
+	s.jobs[id] = &job{subscribers: make(map[chan Event]bool)}
+	return s.queue.Job(id)
+}
+
+func (s *Server) Job(id string) (makemkv.QueueJob, error) {
+	return s.queue.Job(id)
+}
+
+func (s *Server) Jobs() []makemkv.QueueJob {
+	return s.queue.Jobs()
+}
+
+// Cancel stops a running job, finished jobs are left alone.
+func (s *Server) Cancel(id string) error {
+	if err := s.queue.Cancel(id); err != nil {
+		return err
+	}
+	// a job cancelled before the queue started it never reaches onDone
+	if spec, err := s.queue.Job(id); err == nil && spec.State.Finished() {
+		s.onDone(spec)
+	}
+	return nil
+}
+
+// Subscribe returns the events of a job, starting with the messages and the
+// last progress seen so far. The channel is closed after the done event.
+func (s *Server) Subscribe(id string) (<-chan Event, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, nil, makemkv.ErrJobNotFound
+	}
+
+	events := make(chan Event, subscriberBuffer+len(j.messages)+2)
+	for i := range j.messages {
+		events <- Event{Type: "message", Message: &j.messages[i]}
+	}
+	if j.status != nil {
+		status := *j.status
+		events <- Event{Type: "status", Status: &status}
+	}
+	if j.done != nil {
+		spec := *j.done
+		events <- Event{Type: "done", Job: &spec}
+		close(events)
+		return events, func() {}, nil
+	}
+
+	j.subscribers[events] = true
+	return events, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if j.subscribers[events] {
+			delete(j.subscribers, events)
+			close(events)
+		}
+	}, nil
+}
+
+// publish must be called with s.mu held.
+func (j *job) publish(e Event) {
+	for events := range j.subscribers {
+		select {
+		case events <- e:
+		default:
+		}
+	}
+}
+
+func (s *Server) onStatus(spec makemkv.QueueJob, status makemkv.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[spec.Id]; ok {
+		j.status = &status
+		j.publish(Event{Type: "status", Status: &status})
+	}
+}
+
+func (s *Server) onMessage(spec makemkv.QueueJob, msg makemkv.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[spec.Id]; ok {
+		j.messages = append(j.messages, msg)
+		j.publish(Event{Type: "message", Message: &msg})
+	}
+}
+
+// onDone sends the done event of a job and ends its event streams, once.
+func (s *Server) onDone(spec makemkv.QueueJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[spec.Id]
+	if !ok || j.done != nil {
+		return
+	}
+	j.done = &spec
+	j.publish(Event{Type: "done", Job: &spec})
+	for events := range j.subscribers {
+		close(events)
+	}
+	j.subscribers = make(map[chan Event]bool)
+}
+
+func writeJson(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJson(w, code, struct{ Error string }{err.Error()})
+}
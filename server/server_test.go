@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/stretchr/testify/assert"
+)
+
+const fakeMakemkvcon = `#!/bin/sh
+for arg; do
+	case "$arg" in
+	disc:9999)
+		echo 'DRV:0,2,999,12,"BD-RE DRIVE","MOVIE","/dev/sr0"'
+		echo 'DRV:1,256,999,0,"","",""'
+		exit 1
+		;;
+	mkv)
+		echo 'PRGT:5018,0,"Saving to MKV file"'
+		echo 'PRGV:0,32768,65536'
+		echo 'MSG:5005,0,1,"1 titles saved","%1 titles saved","1"'
+		exit 0
+		;;
+	backup)
+		exec sleep 10
+		;;
+	esac
+done
+exit 1
+`
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	return newScriptServer(t, fakeMakemkvcon)
+}
+
+func newScriptServer(t *testing.T, script string) (*Server, *httptest.Server) {
+	bin := filepath.Join(t.TempDir(), "makemkvcon")
+	assert.Nil(t, os.WriteFile(bin, []byte(script), 0755))
+
+	s := New("secret")
+	s.Executable = bin
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return s, ts
+}
+
+func request(t *testing.T, method string, url string, body string, v any) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	if v != nil {
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestAuth(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/jobs")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/jobs?token=secret")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// an empty token must not let requests without one through
+	w := httptest.NewRecorder()
+	New("").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDrives(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	var drives []makemkv.DriveInfo
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, ts.URL+"/drives", "", &drives))
+	assert.Equal(t, 1, len(drives))
+	assert.Equal(t, "MOVIE", drives[0].DiscName)
+	assert.True(t, drives[0].HasDisc())
+}
+
+func TestMkvJobEvents(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	var job makemkv.QueueJob
+	body := `{"Kind":"mkv","Device":"disc:0","TitleId":"0","Destination":"` + t.TempDir() + `"}`
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, ts.URL+"/jobs", body, &job))
+	assert.NotEmpty(t, job.Id)
+
+	resp, err := http.Get(ts.URL + "/jobs/" + job.Id + "/events?token=secret")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var events []Event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			var e Event
+			assert.Nil(t, json.Unmarshal([]byte(data), &e))
+			events = append(events, e)
+		}
+	}
+
+	last := events[len(events)-1]
+	assert.Equal(t, "done", last.Type)
+	assert.Equal(t, makemkv.JobDone, last.Job.State)
+	assert.Equal(t, 1, last.Job.Result.Saved)
+
+	var sawStatus, sawMessage bool
+	for _, e := range events {
+		sawStatus = sawStatus || e.Type == "status" && e.Status.Max == 65536
+		sawMessage = sawMessage || e.Type == "message" && e.Message.Code == 5005
+	}
+	assert.True(t, sawStatus)
+	assert.True(t, sawMessage)
+}
+
+func TestCancel(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	var job makemkv.QueueJob
+	body := `{"Kind":"backup","Device":"disc:0","Destination":"` + t.TempDir() + `"}`
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, ts.URL+"/jobs", body, &job))
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, ts.URL+"/jobs", body, nil))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, ts.URL+"/jobs", `{"Kind":"mkv","Device":"disc:1"}`, nil))
+
+	events, cancel, err := s.Subscribe(job.Id)
+	assert.Nil(t, err)
+	defer cancel()
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodDelete, ts.URL+"/jobs/"+job.Id, "", nil))
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not cancelled")
+	case e := <-events:
+		assert.Equal(t, "done", e.Type)
+		assert.Equal(t, makemkv.JobCancelled, e.Job.State)
+	}
+
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, ts.URL+"/jobs/missing", "", nil))
+}
+
+func waitJob(t *testing.T, ts *httptest.Server, job *makemkv.QueueJob) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.State.Finished() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		request(t, http.MethodGet, ts.URL+"/jobs/"+job.Id, "", job)
+	}
+	assert.True(t, job.State.Finished())
+}
+
+func TestMkvJobAngle(t *testing.T) {
+	args := filepath.Join(t.TempDir(), "args")
+	// the second angle of title 0 is listed as title 1
+	_, ts := newScriptServer(t, `#!/bin/sh
+case "$*" in
+*info*) printf 'TCOUNT:2\nTINFO:0,15,0,"1"\nTINFO:0,16,0,"00800.mpls"\nTINFO:1,15,0,"2"\nTINFO:1,16,0,"00800.mpls"\n' ;;
+*) echo "$@" > `+args+` ;;
+esac
+`)
+
+	body := `{"Kind":"mkv","Device":"disc:0","TitleId":"0","Angle":2,"Destination":"` + t.TempDir() + `"}`
+	var job makemkv.QueueJob
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, ts.URL+"/jobs", body, &job))
+	waitJob(t, ts, &job)
+	data, err := os.ReadFile(args)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "mkv disc:0 1 ")
+}
+
+func TestJobRequestOptions(t *testing.T) {
+	args := filepath.Join(t.TempDir(), "args")
+	_, ts := newScriptServer(t, "#!/bin/sh\necho \"$@\" > "+args+"\n")
+
+	debug := filepath.Join(t.TempDir(), "debug.log")
+	body := `{"Kind":"mkv","Device":"disc:0","TitleId":"0","Destination":"` + t.TempDir() + `",` +
+		`"Options":{"Debug":"` + debug + `","Messages":"/tmp/messages","Minlength":120}}`
+	var job makemkv.QueueJob
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, ts.URL+"/jobs", body, &job))
+	waitJob(t, ts, &job)
+	data, err := os.ReadFile(args)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "--minlength=120")
+	assert.NotContains(t, string(data), "--debug")
+	assert.NotContains(t, string(data), "--messages")
+}
@@ -0,0 +1,144 @@
+package makemkv
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	SettingKey              = "app_Key"
+	SettingDataDir          = "app_DataDir"
+	SettingMinimumLength    = "dvd_MinimumTitleLength"
+	SettingErrorRetryCount  = "io_ErrorRetryCount"
+	SettingIgnoreReadErrors = "io_IgnoreReadErrors"
+	SettingReadBufferSize   = "io_RBufSizeMB"
+	SettingDestinationDir   = "app_DestinationDir"
+)
+
+// Settings is a settings.conf file as written by MakeMKV, one
+// `name = "value"` pair per line. Lines we do not understand are kept as is.
+type Settings struct {
+	path  string
+	lines []string
+}
+
+func DefaultSettingsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".MakeMKV", "settings.conf"), nil
+}
+
+func LoadSettings(path string) (*Settings, error) {
+	s := &Settings{path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s.lines = append(s.lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Settings) Path() string {
+	return s.path
+}
+
+func (s *Settings) Get(name string) (string, bool) {
+	for _, line := range s.lines {
+		if key, value, ok := parseSetting(line); ok && key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (s *Settings) Set(name string, value string) {
+	line := name + " = " + strconv.Quote(value)
+	for i, l := range s.lines {
+		if key, _, ok := parseSetting(l); ok && key == name {
+			s.lines[i] = line
+			return
+		}
+	}
+	s.lines = append(s.lines, line)
+}
+
+func (s *Settings) Delete(name string) {
+	lines := s.lines[:0]
+	for _, l := range s.lines {
+		if key, _, ok := parseSetting(l); ok && key == name {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	s.lines = lines
+}
+
+// Save replaces the file atomically so a running makemkvcon never sees a
+// partially written configuration.
+func (s *Settings) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".settings.conf.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	// CreateTemp makes the file private, keep the mode of the original
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(s.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	for _, line := range s.lines {
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func parseSetting(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", false
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	return key, value, true
+}
@@ -0,0 +1,84 @@
+package makemkv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const settingsInput = `#
+# MakeMKV settings file, written by MakeMKV v1.17.6 linux(x64-release)
+#
+
+app_DataDir = "/home/user/.MakeMKV/data"
+app_DestinationType = "2"
+app_Key = "T-old"
+dvd_MinimumTitleLength = "120"
+sdf_Stop = ""
+`
+
+func TestSettingsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.conf")
+	assert.Nil(t, os.WriteFile(path, []byte(settingsInput), 0640))
+
+	s, err := LoadSettings(path)
+	assert.Nil(t, err)
+	value, ok := s.Get(SettingMinimumLength)
+	assert.True(t, ok)
+	assert.Equal(t, "120", value)
+	value, ok = s.Get("sdf_Stop")
+	assert.True(t, ok)
+	assert.Equal(t, "", value)
+	_, ok = s.Get(SettingReadBufferSize)
+	assert.False(t, ok)
+
+	s.Set(SettingMinimumLength, "600")
+	s.Set(SettingReadBufferSize, "1024")
+	s.Delete("app_DestinationType")
+	assert.Nil(t, s.Save())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, `#
+# MakeMKV settings file, written by MakeMKV v1.17.6 linux(x64-release)
+#
+
+app_DataDir = "/home/user/.MakeMKV/data"
+app_Key = "T-old"
+dvd_MinimumTitleLength = "600"
+sdf_Stop = ""
+io_RBufSizeMB = "1024"
+`, string(data))
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestWriteKey(t *testing.T) {
+	key := "T-" + "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUV0123456789@_ab"
+	path := filepath.Join(t.TempDir(), "settings.conf")
+	assert.Nil(t, os.WriteFile(path, []byte(settingsInput), 0644))
+
+	assert.Equal(t, ErrInvalidKeyFormat, WriteKey(path, "T-short"))
+	assert.Nil(t, WriteKey(path, " "+key+"\n"))
+
+	s, err := LoadSettings(path)
+	assert.Nil(t, err)
+	value, _ := s.Get(SettingKey)
+	assert.Equal(t, key, value)
+	value, _ = s.Get(SettingDataDir)
+	assert.Equal(t, "/home/user/.MakeMKV/data", value)
+	value, _ = s.Get("app_DestinationType")
+	assert.Equal(t, "2", value)
+
+	// a missing file is created
+	path = filepath.Join(t.TempDir(), ".MakeMKV", "settings.conf")
+	assert.Nil(t, WriteKey(path, key))
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, `app_Key = "`+key+"\"\n", string(data))
+}
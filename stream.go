@@ -0,0 +1,269 @@
+package makemkv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultStreamAddress = "127.0.0.1"
+	defaultStreamPort    = 51000
+)
+
+var ErrStreamNotReady = errors.New("makemkv: stream server did not become ready")
+
+type StreamOptions struct {
+	MkvOptions
+	BindAddress *string
+	BindPort    *int
+	Upnp        *bool
+	// how long Start waits for the server to answer before giving up
+	ReadyTimeout time.Duration
+}
+
+func (o StreamOptions) toStrings() []string {
+	result := o.MkvOptions.toStrings()
+	if o.Upnp != nil {
+		result = append(result, "--upnp="+strconv.FormatBool(*o.Upnp))
+	}
+	if o.BindAddress != nil {
+		result = append(result, "--bindip="+*o.BindAddress)
+	}
+	if o.BindPort != nil {
+		result = append(result, "--bindport="+strconv.Itoa(*o.BindPort))
+	}
+	return result
+}
+
+type StreamJob struct {
+	device  Device
+	options StreamOptions
+}
+
+func Stream(device Device, opts StreamOptions) *StreamJob {
+	return &StreamJob{
+		device:  device,
+		options: opts,
+	}
+}
+
+type StreamServer struct {
+	Address string
+	Port    int
+
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+	client *http.Client
+}
+
+// Start launches makemkvcon in stream mode and blocks until the web
+// interface answers. The server runs until ctx is cancelled or Stop is called.
+func (j *StreamJob) Start(ctx context.Context) (*StreamServer, error) {
+	address := defaultStreamAddress
+	if j.options.BindAddress != nil {
+		address = *j.options.BindAddress
+	}
+	port := defaultStreamPort
+	if j.options.BindPort != nil {
+		port = *j.options.BindPort
+	}
+	timeout := j.options.ReadyTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	dev := DeviceString(j.device)
+	options := append(j.options.toStrings(), []string{"stream", dev}...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, j.options.executable(), options...)
+	cmd.Stdout = io.Discard
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s := &StreamServer{
+		Address: address,
+		Port:    port,
+		cmd:     cmd,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+	go func() {
+		s.err = cmd.Wait()
+		close(s.done)
+	}()
+
+	if err := s.waitReady(ctx, timeout); err != nil {
+		s.Stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *StreamServer) waitReady(ctx context.Context, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Healthy(ctx); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			if s.err != nil {
+				return fmt.Errorf("%w: %v", ErrStreamNotReady, s.err)
+			}
+			return ErrStreamNotReady
+		case <-deadline.C:
+			return ErrStreamNotReady
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *StreamServer) URL() string {
+	host := s.Address
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(s.Port))
+}
+
+// TitleURL is the default stream URL of a title. The server publishes the
+// actual links on its title pages, TitleURLs reads them from there.
+func (s *StreamServer) TitleURL(titleId int) string {
+	return s.URL() + "/stream/title" + strconv.Itoa(titleId) + ".m2ts"
+}
+
+// Healthy checks that the web interface of the stream server answers.
+func (s *StreamServer) Healthy(ctx context.Context) error {
+	_, err := s.get(ctx, "/")
+	return err
+}
+
+var (
+	linkRegex       = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+	titleLinkRegex  = regexp.MustCompile(`title(\d+)`)
+	streamLinkRegex = regexp.MustCompile(`(?i)\.(m2ts|ts|mkv|mpg|vob)$`)
+)
+
+// TitleURLs lists the titles published by the server, keyed by title id.
+// Links on the title list that point at a stream are used as they are,
+// links to a title page are followed to the stream linked there. Titles
+// whose page has no stream link get TitleURL.
+func (s *StreamServer) TitleURLs(ctx context.Context) (map[int]string, error) {
+	base, err := url.Parse(s.URL() + "/web/titles")
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.get(ctx, base.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[int]string)
+	for _, link := range links(base, body) {
+		m := titleLinkRegex.FindStringSubmatch(link.Path)
+		if m == nil {
+			continue
+		}
+		id, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		if _, ok := urls[id]; ok {
+			continue
+		}
+		if streamLinkRegex.MatchString(link.Path) {
+			urls[id] = link.String()
+			continue
+		}
+
+		urls[id] = s.TitleURL(id)
+		page, err := s.get(ctx, link.RequestURI())
+		if err != nil {
+			return nil, err
+		}
+		for _, stream := range links(link, page) {
+			if streamLinkRegex.MatchString(stream.Path) {
+				urls[id] = stream.String()
+				break
+			}
+		}
+	}
+	return urls, nil
+}
+
+func links(base *url.URL, body []byte) []*url.URL {
+	var result []*url.URL
+	for _, m := range linkRegex.FindAllSubmatch(body, -1) {
+		if u, err := base.Parse(string(m[1])); err == nil {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+func (s *StreamServer) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("makemkv: stream server returned %s", resp.Status)
+	}
+	return body, nil
+}
+
+func (s *StreamServer) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *StreamServer) Wait() error {
+	<-s.done
+	return s.err
+}
+
+func (s *StreamServer) Stop() error {
+	s.cancel()
+	<-s.done
+	if errors.Is(s.err, context.Canceled) {
+		return nil
+	}
+	if exitErr, ok := s.err.(*exec.ExitError); ok && !exitErr.Exited() {
+		// killed by us
+		return nil
+	}
+	return s.err
+}
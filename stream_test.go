@@ -0,0 +1,135 @@
+package makemkv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testStreamServer(t *testing.T, handler http.Handler) *StreamServer {
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return &StreamServer{Address: host, Port: p, client: ts.Client()}
+}
+
+func TestStreamServerTitleURLs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><a href="/web/titles">titles</a></html>`)
+	})
+	mux.HandleFunc("/web/titles", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="title0">Title 0</a> <a href='title1'>Title 1</a> <a href="/stream/title2.m2ts">Title 2</a> <a href="/web/info">Info</a>`)
+	})
+	mux.HandleFunc("/web/title0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="../web/titles">back</a><a href="/stream/title0.m2ts">stream</a>`)
+	})
+	mux.HandleFunc("/web/title1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>no link</p>`)
+	})
+	s := testStreamServer(t, mux)
+
+	assert.Nil(t, s.Healthy(context.Background()))
+	urls, err := s.TitleURLs(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{
+		0: s.URL() + "/stream/title0.m2ts",
+		1: s.TitleURL(1),
+		2: s.URL() + "/stream/title2.m2ts",
+	}, urls)
+}
+
+func TestStreamServerUnhealthy(t *testing.T) {
+	s := testStreamServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "starting", http.StatusServiceUnavailable)
+	}))
+	assert.NotNil(t, s.Healthy(context.Background()))
+	_, err := s.TitleURLs(context.Background())
+	assert.NotNil(t, err)
+}
+
+// fakeStreamer writes a makemkvcon that records its arguments and runs
+// script in stream mode. The web interface is played by the test.
+func fakeStreamer(t *testing.T, script string) (bin string, args string) {
+	dir := t.TempDir()
+	bin = filepath.Join(dir, "makemkvcon")
+	args = filepath.Join(dir, "args")
+	assert.Nil(t, os.WriteFile(bin, []byte("#!/bin/sh\necho \"$@\" > "+args+"\n"+script), 0755))
+	return bin, args
+}
+
+func TestStreamJobStartStop(t *testing.T) {
+	// the web interface answers from the second request on
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 2 {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "<html></html>")
+	}))
+	defer ts.Close()
+	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	bin, args := fakeStreamer(t, "exec sleep 30\n")
+	opts := StreamOptions{MkvOptions: MkvOptions{Executable: bin}, BindAddress: &host, BindPort: &p, ReadyTimeout: 10 * time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := Stream(NewDiscDevice(0), opts).Start(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, ts.URL, s.URL())
+	assert.True(t, requests.Load() >= 2)
+	data, err := os.ReadFile(args)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(string(data)), "--bindport="+port+" stream disc:0"), string(data))
+
+	select {
+	case <-s.Done():
+		t.Fatal("stream server exited while running")
+	default:
+	}
+	assert.Nil(t, s.Stop())
+	<-s.Done()
+
+	// cancelling the context stops the server as well
+	s, err = Stream(NewDiscDevice(0), opts).Start(ctx)
+	assert.Nil(t, err)
+	cancel()
+	<-s.Done()
+	assert.Nil(t, s.Stop())
+}
+
+func TestStreamJobExitsEarly(t *testing.T) {
+	// nothing listens on the port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+	p, _ := strconv.Atoi(port)
+
+	bin, _ := fakeStreamer(t, "exit 1\n")
+	opts := StreamOptions{MkvOptions: MkvOptions{Executable: bin}, BindAddress: &host, BindPort: &p, ReadyTimeout: 10 * time.Second}
+	start := time.Now()
+	_, err = Stream(NewDiscDevice(0), opts).Start(context.Background())
+	assert.True(t, errors.Is(err, ErrStreamNotReady))
+	assert.ErrorContains(t, err, "exit status 1")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
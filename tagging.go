@@ -0,0 +1,128 @@
+package makemkv
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aravance/go-makemkv/matroska"
+)
+
+// WriteTags sets the segment title, track names, languages and flags and the
+// global tags of a ripped file from its scan and user metadata. The file is
+// edited in place, the clusters are never rewritten.
+func WriteTags(output OutputFile, title TitleInfo, meta Metadata) error {
+	if output.Path == "" {
+		return fmt.Errorf("makemkv: title %d has no output file", output.TitleId)
+	}
+	file, err := matroska.Open(output.Path)
+	if err != nil {
+		return err
+	}
+
+	edits := matroska.Edits{Tracks: make(map[int]matroska.TrackEdits)}
+	if name := segmentTitle(title, meta); name != "" {
+		edits.Title = &name
+	}
+
+	// tracks are written in scan order, so the n-th track of a type in the
+	// file is the n-th stream of that type in the title
+	var video, audio, subtitle int
+	for _, track := range file.Tracks {
+		var te matroska.TrackEdits
+		switch track.Type {
+		case matroska.TrackVideo:
+			if video >= len(title.VideoStreams) {
+				continue
+			}
+			s := title.VideoStreams[video]
+			te = trackEdits(s.Name, "", video == 0, false)
+			video++
+		case matroska.TrackAudio:
+			if audio >= len(title.AudioStreams) {
+				continue
+			}
+			s := title.AudioStreams[audio]
+			te = trackEdits(s.Name, s.LangCode, audio == 0, false)
+			audio++
+		case matroska.TrackSubtitle:
+			if subtitle >= len(title.SubtitleStreams) {
+				continue
+			}
+			s := title.SubtitleStreams[subtitle]
+			forced := s.StreamFlags&StreamFlagForcedSubtitles != 0
+			te = trackEdits(s.Name, s.LangCode, forced, forced)
+			subtitle++
+		default:
+			continue
+		}
+		edits.Tracks[track.Number] = te
+	}
+
+	edits.Tags = globalTags(meta)
+	return matroska.Edit(output.Path, edits)
+}
+
+func segmentTitle(title TitleInfo, meta Metadata) string {
+	switch {
+	case meta.Show != "" && meta.Episode > 0:
+		return fmt.Sprintf("%s S%02dE%02d", meta.Show, meta.Season, meta.Episode)
+	case meta.Title != "" && meta.Year > 0:
+		return fmt.Sprintf("%s (%d)", meta.Title, meta.Year)
+	case meta.Title != "":
+		return meta.Title
+	default:
+		return title.Name
+	}
+}
+
+func trackEdits(name string, langCode string, isDefault bool, forced bool) matroska.TrackEdits {
+	te := matroska.TrackEdits{
+		Default: &isDefault,
+		Forced:  &forced,
+	}
+	if name != "" {
+		te.Name = &name
+	}
+	if langCode != "" {
+		te.Language = &langCode
+	}
+	return te
+}
+
+// globalTags follows the Matroska tagging guidelines: the collection (show
+// and season) at target 70/60, the movie or episode itself at 50.
+func globalTags(meta Metadata) []matroska.Tag {
+	var tags []matroska.Tag
+	var item []matroska.SimpleTag
+	add := func(name string, value string) {
+		if value != "" {
+			item = append(item, matroska.SimpleTag{Name: name, Value: value})
+		}
+	}
+
+	if meta.Show != "" {
+		tags = append(tags, matroska.Tag{TargetTypeValue: 70, TargetType: "COLLECTION", SimpleTags: []matroska.SimpleTag{
+			{Name: "TITLE", Value: meta.Show},
+		}})
+		if meta.Season > 0 {
+			tags = append(tags, matroska.Tag{TargetTypeValue: 60, TargetType: "SEASON", SimpleTags: []matroska.SimpleTag{
+				{Name: "PART_NUMBER", Value: strconv.Itoa(meta.Season)},
+			}})
+		}
+		if meta.Episode > 0 {
+			add("PART_NUMBER", strconv.Itoa(meta.Episode))
+		}
+	}
+	add("TITLE", meta.Title)
+	if meta.Year > 0 {
+		add("DATE_RELEASED", strconv.Itoa(meta.Year))
+	}
+	add("EDITION", meta.Edition)
+	add("IMDB", meta.ImdbId)
+	add("TMDB", meta.TmdbId)
+
+	if len(item) > 0 {
+		tags = append(tags, matroska.Tag{TargetTypeValue: 50, SimpleTags: item})
+	}
+	return tags
+}
@@ -0,0 +1,118 @@
+package makemkv
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aravance/go-makemkv/matroska"
+	"github.com/stretchr/testify/assert"
+)
+
+// mkvElement encodes an EBML element with the shortest size field, so any
+// value that grows no longer fits in its old place.
+func mkvElement(id []byte, children ...[]byte) []byte {
+	var data []byte
+	for _, c := range children {
+		data = append(data, c...)
+	}
+	size := []byte{0x80 | byte(len(data))}
+	if len(data) >= 127 {
+		size = []byte{0x40 | byte(len(data)>>8), byte(len(data))}
+	}
+	return append(append(id, size...), data...)
+}
+
+func mkvString(id []byte, s string) []byte {
+	return mkvElement(id, []byte(s))
+}
+
+func mkvUint(id []byte, v byte) []byte {
+	return mkvElement(id, []byte{v})
+}
+
+// writeRippedFile writes a file with a seek head that points at the tracks
+// and has room for more entries.
+func writeRippedFile(t *testing.T) string {
+	var (
+		idSeekHead     = []byte{0x11, 0x4D, 0x9B, 0x74}
+		idSeek         = []byte{0x4D, 0xBB}
+		idSeekID       = []byte{0x53, 0xAB}
+		idSeekPosition = []byte{0x53, 0xAC}
+		idVoid         = []byte{0xEC}
+		idInfo         = []byte{0x15, 0x49, 0xA9, 0x66}
+		idTitle        = []byte{0x7B, 0xA9}
+		idTracks       = []byte{0x16, 0x54, 0xAE, 0x6B}
+		idTrackEntry   = []byte{0xAE}
+		idTrackNumber  = []byte{0xD7}
+		idTrackType    = []byte{0x83}
+		idCodecID      = []byte{0x86}
+		idLanguage     = []byte{0x22, 0xB5, 0x9C}
+		idCluster      = []byte{0x1F, 0x43, 0xB6, 0x75}
+	)
+	info := mkvElement(idInfo, mkvString(idTitle, "MOVIE"))
+	tracks := mkvElement(idTracks,
+		mkvElement(idTrackEntry, mkvUint(idTrackNumber, 1), mkvUint(idTrackType, 1), mkvString(idCodecID, "V_MPEG4/ISO/AVC")),
+		mkvElement(idTrackEntry, mkvUint(idTrackNumber, 2), mkvUint(idTrackType, 2), mkvString(idCodecID, "A_AC3"), mkvString(idLanguage, "und")),
+		mkvElement(idTrackEntry, mkvUint(idTrackNumber, 3), mkvUint(idTrackType, 17), mkvString(idCodecID, "S_HDMV/PGS")),
+	)
+	// 19 bytes of seek head and 64 bytes of Void
+	seekHead := mkvElement(idSeekHead, mkvElement(idSeek, mkvElement(idSeekID, idTracks), mkvUint(idSeekPosition, byte(19+64+len(info)))))
+	void := mkvElement(idVoid, make([]byte, 62))
+	segment := mkvElement([]byte{0x18, 0x53, 0x80, 0x67}, seekHead, void, info, tracks, mkvElement(idCluster, make([]byte, 100)))
+	header := mkvElement([]byte{0x1A, 0x45, 0xDF, 0xA3}, mkvString([]byte{0x42, 0x82}, "matroska"))
+
+	path := filepath.Join(t.TempDir(), "MOVIE_t00.mkv")
+	assert.Nil(t, os.WriteFile(path, append(header, segment...), 0644))
+	return path
+}
+
+func TestWriteTags(t *testing.T) {
+	path := writeRippedFile(t)
+	title := TitleInfo{
+		Name:         "MOVIE",
+		VideoStreams: []VideoStreamInfo{{Name: "Mpeg4 AVC"}},
+		AudioStreams: []AudioStreamInfo{{Name: "Surround 5.1", LangCode: "fra"}},
+		SubtitleStreams: []SubtitleStreamInfo{
+			{LangCode: "eng", StreamFlags: StreamFlagForcedSubtitles},
+		},
+	}
+	meta := Metadata{Title: "Alien", Year: 1979, ImdbId: "tt0078748"}
+	assert.Nil(t, WriteTags(OutputFile{Path: path}, title, meta))
+
+	file, err := matroska.Open(path)
+	assert.Nil(t, err)
+	assert.False(t, file.Truncated)
+	assert.Equal(t, "Alien (1979)", file.Title)
+	assert.Equal(t, 3, len(file.Tracks))
+	assert.Equal(t, "Mpeg4 AVC", file.Tracks[0].Name)
+	assert.True(t, file.Tracks[0].Default)
+	assert.Equal(t, "Surround 5.1", file.Tracks[1].Name)
+	assert.Equal(t, "fra", file.Tracks[1].Language)
+	assert.Equal(t, "A_AC3", file.Tracks[1].CodecId)
+	assert.True(t, file.Tracks[2].Forced)
+	assert.True(t, file.Tracks[2].Default)
+	assert.Equal(t, "eng", file.Tracks[2].Language)
+	value, _ := file.Tag("DATE_RELEASED")
+	assert.Equal(t, "1979", value)
+	value, _ = file.Tag("IMDB")
+	assert.Equal(t, "tt0078748", value)
+
+	// the grown info was moved behind the cluster
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	cluster := bytes.Index(data, []byte{0x1F, 0x43, 0xB6, 0x75})
+	assert.Greater(t, bytes.LastIndex(data, []byte{0x15, 0x49, 0xA9, 0x66}), cluster)
+
+	// tagging again replaces the values instead of adding to them
+	meta.ImdbId = "tt0090605"
+	assert.Nil(t, WriteTags(OutputFile{Path: path}, title, meta))
+	file, err = matroska.Open(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(file.Tags))
+	value, _ = file.Tag("IMDB")
+	assert.Equal(t, "tt0090605", value)
+
+	assert.NotNil(t, WriteTags(OutputFile{TitleId: 1}, title, meta))
+}
@@ -0,0 +1,66 @@
+package makemkv
+
+import (
+	"sort"
+	"time"
+)
+
+// MainFeature picks the longest title, preferring the larger one when two
+// titles have the same length.
+func MainFeature(info DiscInfo) (TitleInfo, bool) {
+	if len(info.Titles) == 0 {
+		return TitleInfo{}, false
+	}
+	main := info.Titles[0]
+	for _, t := range info.Titles[1:] {
+		if t.Duration > main.Duration || (t.Duration == main.Duration && t.FileSize > main.FileSize) {
+			main = t
+		}
+	}
+	return main, true
+}
+
+const (
+	minEpisodeLength = 15 * time.Minute
+	maxEpisodeLength = 75 * time.Minute
+	// how far an episode may be from the median episode length
+	episodeTolerance = 0.25
+)
+
+// Episodes guesses which titles are the episodes of a series disc: titles of
+// episode length that are close to the median length of all such titles.
+// Titles that are the concatenation of several episodes ("play all") fall
+// outside the range and are left out.
+func Episodes(info DiscInfo) []TitleInfo {
+	var candidates []TitleInfo
+	for _, t := range info.Titles {
+		if t.Duration >= minEpisodeLength && t.Duration <= maxEpisodeLength {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) < 2 {
+		return nil
+	}
+
+	durations := make([]time.Duration, len(candidates))
+	for i, t := range candidates {
+		durations[i] = t.Duration
+	}
+	sort.Slice(durations, func(a, b int) bool { return durations[a] < durations[b] })
+	median := durations[len(durations)/2]
+
+	var episodes []TitleInfo
+	for _, t := range candidates {
+		diff := float64(t.Duration - median)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= float64(median)*episodeTolerance {
+			episodes = append(episodes, t)
+		}
+	}
+	if len(episodes) < 2 {
+		return nil
+	}
+	return episodes
+}
@@ -0,0 +1,347 @@
+// Package tmdb is a makemkv.MetadataProvider for The Movie Database API and
+// compatible servers.
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/aravance/go-makemkv"
+)
+
+const DefaultBaseURL = "https://api.themoviedb.org/3"
+
+type Client struct {
+	BaseURL string
+	// API read access token, sent as bearer token
+	Token string
+	// ISO 639-1 language of the titles, the API default if empty
+	Language   string
+	HTTPClient *http.Client
+	// number of search results per kind that are looked at in detail, 3 if 0
+	Candidates int
+
+	mu sync.Mutex
+	// matches by disc fingerprint
+	seen map[string][]makemkv.MetadataMatch
+}
+
+func New(token string) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Token: token}
+}
+
+// APIError is returned for responses other than 200.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("tmdb: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("tmdb: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Lookup searches movies and series for the disc title and scores the
+// candidates by title, year, runtime and episode runtimes. The matches are
+// remembered by the disc fingerprint, a disc that is inserted again is not
+// looked up again.
+func (c *Client) Lookup(ctx context.Context, q makemkv.DiscQuery) ([]makemkv.MetadataMatch, error) {
+	if q.Title == "" {
+		return nil, nil
+	}
+	if q.Fingerprint != "" {
+		c.mu.Lock()
+		matches, ok := c.seen[q.Fingerprint]
+		c.mu.Unlock()
+		if ok {
+			return append([]makemkv.MetadataMatch(nil), matches...), nil
+		}
+	}
+	movies, err := c.movies(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	series, err := c.series(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := append(movies, series...)
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if q.Fingerprint != "" {
+		c.mu.Lock()
+		if c.seen == nil {
+			c.seen = make(map[string][]makemkv.MetadataMatch)
+		}
+		c.seen[q.Fingerprint] = append([]makemkv.MetadataMatch(nil), matches...)
+		c.mu.Unlock()
+	}
+	return matches, nil
+}
+
+func (c *Client) movies(ctx context.Context, q makemkv.DiscQuery) ([]makemkv.MetadataMatch, error) {
+	params := url.Values{"query": {q.Title}}
+	if q.Year > 0 {
+		params.Set("year", strconv.Itoa(q.Year))
+	}
+	var search struct {
+		Results []struct {
+			Id int `json:"id"`
+		} `json:"results"`
+	}
+	if err := c.get(ctx, "/search/movie", params, &search); err != nil {
+		return nil, err
+	}
+
+	var matches []makemkv.MetadataMatch
+	for i, r := range search.Results {
+		if i >= c.candidates() {
+			break
+		}
+		var movie struct {
+			Id          int    `json:"id"`
+			Title       string `json:"title"`
+			ReleaseDate string `json:"release_date"`
+			Runtime     int    `json:"runtime"`
+			ImdbId      string `json:"imdb_id"`
+		}
+		if err := c.get(ctx, "/movie/"+strconv.Itoa(r.Id), nil, &movie); err != nil {
+			return nil, err
+		}
+		m := makemkv.MetadataMatch{
+			Kind:    makemkv.MediaMovie,
+			Title:   movie.Title,
+			Year:    year(movie.ReleaseDate),
+			Runtime: time.Duration(movie.Runtime) * time.Minute,
+			ImdbId:  movie.ImdbId,
+			TmdbId:  strconv.Itoa(movie.Id),
+		}
+		m.Score = 0.6*similarity(q.Title, m.Title) + 0.3*runtimeFit(q.Runtime, m.Runtime) + 0.1*yearFit(q.Year, m.Year)
+		// several episodes on the disc make a movie unlikely
+		if len(q.EpisodeRuntimes) > 1 {
+			m.Score *= 0.7
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+func (c *Client) series(ctx context.Context, q makemkv.DiscQuery) ([]makemkv.MetadataMatch, error) {
+	params := url.Values{"query": {q.Title}}
+	var search struct {
+		Results []struct {
+			Id           int    `json:"id"`
+			Name         string `json:"name"`
+			FirstAirDate string `json:"first_air_date"`
+		} `json:"results"`
+	}
+	if err := c.get(ctx, "/search/tv", params, &search); err != nil {
+		return nil, err
+	}
+
+	season := q.Season
+	if season == 0 {
+		season = 1
+	}
+	var matches []makemkv.MetadataMatch
+	for i, r := range search.Results {
+		if i >= c.candidates() {
+			break
+		}
+		id := strconv.Itoa(r.Id)
+		var details struct {
+			Episodes []struct {
+				Number  int    `json:"episode_number"`
+				Season  int    `json:"season_number"`
+				Name    string `json:"name"`
+				Runtime int    `json:"runtime"`
+			} `json:"episodes"`
+		}
+		err := c.get(ctx, "/tv/"+id+"/season/"+strconv.Itoa(season), nil, &details)
+		if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		var external struct {
+			ImdbId string `json:"imdb_id"`
+		}
+		if err := c.get(ctx, "/tv/"+id+"/external_ids", nil, &external); err != nil {
+			return nil, err
+		}
+
+		m := makemkv.MetadataMatch{
+			Kind:   makemkv.MediaSeries,
+			Title:  r.Name,
+			Year:   year(r.FirstAirDate),
+			ImdbId: external.ImdbId,
+			TmdbId: id,
+			Season: season,
+		}
+		for _, e := range details.Episodes {
+			m.Episodes = append(m.Episodes, makemkv.Episode{
+				Season:  e.Season,
+				Number:  e.Number,
+				Title:   e.Name,
+				Runtime: time.Duration(e.Runtime) * time.Minute,
+			})
+		}
+		m.Score = 0.6*similarity(q.Title, m.Title) + 0.3*episodeFit(q.EpisodeRuntimes, m.Episodes) + 0.1*yearFit(q.Year, m.Year)
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+func (c *Client) candidates() int {
+	if c.Candidates > 0 {
+		return c.Candidates
+	}
+	return 3
+}
+
+func (c *Client) get(ctx context.Context, path string, params url.Values, v any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	if c.Language != "" {
+		params.Set("language", c.Language)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	u := strings.TrimSuffix(base, "/") + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Message string `json:"status_message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return &APIError{StatusCode: resp.StatusCode, Message: body.Message}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func year(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	y, _ := strconv.Atoi(date[:4])
+	return y
+}
+
+// similarity compares titles by their words, ignoring case, punctuation
+// and a leading article.
+func similarity(a string, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	if strings.Join(wa, " ") == strings.Join(wb, " ") {
+		return 1
+	}
+	set := make(map[string]bool, len(wa))
+	for _, w := range wa {
+		set[w] = true
+	}
+	common := 0
+	union := len(set)
+	for _, w := range wb {
+		if set[w] {
+			common++
+			set[w] = false
+		} else if _, seen := set[w]; !seen {
+			union++
+		}
+	}
+	return 0.9 * float64(common) / float64(union)
+}
+
+func words(s string) []string {
+	w := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(w) > 1 && (w[0] == "the" || w[0] == "a" || w[0] == "an") {
+		w = w[1:]
+	}
+	return w
+}
+
+// runtimeFit is 1 for the same runtime and falls to 0 at 15 minutes off.
+func runtimeFit(disc time.Duration, runtime time.Duration) float64 {
+	if disc == 0 || runtime == 0 {
+		return 0.5
+	}
+	diff := (disc - runtime).Minutes()
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff >= 15 {
+		return 0
+	}
+	return 1 - diff/15
+}
+
+// episodeFit is the share of episode titles of the disc that are within
+// five minutes of an episode of the season.
+func episodeFit(disc []time.Duration, episodes []makemkv.Episode) float64 {
+	if len(episodes) == 0 {
+		return 0
+	}
+	if len(disc) == 0 {
+		return 0.3
+	}
+	fit := 0
+	for _, d := range disc {
+		for _, e := range episodes {
+			if diff := d - e.Runtime; e.Runtime > 0 && diff < 5*time.Minute && diff > -5*time.Minute {
+				fit++
+				break
+			}
+		}
+	}
+	return float64(fit) / float64(len(disc))
+}
+
+func yearFit(want int, got int) float64 {
+	switch {
+	case want == 0:
+		return 0.5
+	case want == got:
+		return 1
+	default:
+		return 0
+	}
+}
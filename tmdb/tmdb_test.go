@@ -0,0 +1,107 @@
+package tmdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv"
+	"github.com/aravance/go-makemkv/tmdb/tmdbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T) *Client {
+	c, _ := newTestServer(t)
+	return c
+}
+
+func newTestServer(t *testing.T) (*Client, *tmdbtest.Server) {
+	server := tmdbtest.NewServer("token")
+	t.Cleanup(server.Close)
+	c := New("token")
+	c.BaseURL = server.URL + "/3"
+	return c, server
+}
+
+func TestLookupMovie(t *testing.T) {
+	c := newTestClient(t)
+	info := makemkv.DiscInfo{Name: "ALIEN_D1", Titles: []makemkv.TitleInfo{
+		{Id: 0, Duration: 116*time.Minute + 40*time.Second},
+		{Id: 1, Duration: 3 * time.Minute},
+	}}
+
+	matches, err := c.Lookup(context.Background(), makemkv.NewDiscQuery(info))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(matches))
+	best := matches[0]
+	assert.Equal(t, makemkv.MediaMovie, best.Kind)
+	assert.Equal(t, "Alien", best.Title)
+	assert.Equal(t, 1979, best.Year)
+	assert.Equal(t, "tt0078748", best.ImdbId)
+	assert.Equal(t, "348", best.TmdbId)
+	assert.True(t, best.Score > matches[1].Score)
+
+	meta := best.MetadataFunc(info)(info.Titles[0])
+	assert.Equal(t, makemkv.Metadata{Title: "Alien", Year: 1979, ImdbId: "tt0078748", TmdbId: "348"}, meta)
+}
+
+func TestLookupSeries(t *testing.T) {
+	c := newTestClient(t)
+	info := makemkv.DiscInfo{Name: "FIREFLY_S1_D2", Titles: []makemkv.TitleInfo{
+		{Id: 0, Duration: 172 * time.Minute},
+		{Id: 1, Duration: 43*time.Minute + 10*time.Second},
+		{Id: 2, Duration: 42*time.Minute + 50*time.Second},
+		{Id: 3, Duration: 43*time.Minute + 5*time.Second},
+	}}
+	query := makemkv.NewDiscQuery(info)
+	assert.Equal(t, "Firefly", query.Title)
+	assert.Equal(t, 1, query.Season)
+	assert.Equal(t, 2, query.Disc)
+
+	matches, err := c.Lookup(context.Background(), query)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+	best := matches[0]
+	assert.Equal(t, makemkv.MediaSeries, best.Kind)
+	assert.Equal(t, "tt0303461", best.ImdbId)
+	assert.Equal(t, 6, len(best.Episodes))
+
+	// the second disc starts after the three episodes of the first
+	meta := best.MetadataFunc(info)
+	assert.Equal(t, 4, meta(info.Titles[1]).Episode)
+	assert.Equal(t, "Shindig", meta(info.Titles[1]).Title)
+	assert.Equal(t, 6, meta(info.Titles[3]).Episode)
+	assert.Equal(t, "Firefly", meta(info.Titles[0]).Show)
+	assert.Equal(t, 0, meta(info.Titles[0]).Episode)
+}
+
+func TestLookupUnauthorized(t *testing.T) {
+	c := newTestClient(t)
+	c.Token = "wrong"
+	_, err := c.Lookup(context.Background(), makemkv.DiscQuery{Title: "Alien"})
+	apiErr, ok := err.(*APIError)
+	assert.True(t, ok)
+	assert.Equal(t, 401, apiErr.StatusCode)
+	assert.Equal(t, "Invalid API key", apiErr.Message)
+}
+
+func TestLookupFingerprint(t *testing.T) {
+	c, server := newTestServer(t)
+	info := makemkv.DiscInfo{Name: "ALIEN", Titles: []makemkv.TitleInfo{{Id: 0, Duration: 117 * time.Minute}}}
+	query := makemkv.NewDiscQuery(info)
+	assert.Equal(t, makemkv.Fingerprint(info), query.Fingerprint)
+
+	matches, err := c.Lookup(context.Background(), query)
+	assert.Nil(t, err)
+	assert.Equal(t, "Alien", matches[0].Title)
+
+	// the disc is known, the API is not asked again
+	server.Close()
+	again, err := c.Lookup(context.Background(), query)
+	assert.Nil(t, err)
+	assert.Equal(t, matches, again)
+
+	query.Fingerprint = ""
+	_, err = c.Lookup(context.Background(), query)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,139 @@
+// Package tmdbtest is a fake of the parts of the TMDB API the tmdb client
+// uses, for tests that should not reach the network.
+package tmdbtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+type Movie struct {
+	Id          int
+	Title       string
+	ReleaseDate string
+	// minutes
+	Runtime int
+	ImdbId  string
+}
+
+type Show struct {
+	Id           int
+	Name         string
+	FirstAirDate string
+	ImdbId       string
+	Seasons      map[int][]Episode
+}
+
+type Episode struct {
+	Number int
+	Name   string
+	// minutes
+	Runtime int
+}
+
+// Server answers search, movie, season and external id requests from
+// Movies and Shows, which can be changed before the first request.
+type Server struct {
+	*httptest.Server
+	Token  string
+	Movies []Movie
+	Shows  []Show
+}
+
+// NewServer starts a server that requires token as bearer token, with a few
+// movies and a show already in it.
+func NewServer(token string) *Server {
+	s := &Server{
+		Token: token,
+		Movies: []Movie{
+			{Id: 348, Title: "Alien", ReleaseDate: "1979-05-25", Runtime: 117, ImdbId: "tt0078748"},
+			{Id: 679, Title: "Aliens", ReleaseDate: "1986-07-18", Runtime: 137, ImdbId: "tt0090605"},
+			{Id: 8077, Title: "Alien³", ReleaseDate: "1992-05-22", Runtime: 114, ImdbId: "tt0103644"},
+		},
+		Shows: []Show{{
+			Id: 1100, Name: "Firefly", FirstAirDate: "2002-09-20", ImdbId: "tt0303461",
+			Seasons: map[int][]Episode{1: {
+				{1, "Serenity", 86},
+				{2, "The Train Job", 43},
+				{3, "Bushwhacked", 43},
+				{4, "Shindig", 43},
+				{5, "Safe", 43},
+				{6, "Our Mrs. Reynolds", 43},
+			}},
+		}},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]any{"status_code": 7, "status_message": "Invalid API key"})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/3"), "/"), "/")
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	switch {
+	case len(parts) == 2 && parts[0] == "search" && parts[1] == "movie":
+		var results []map[string]any
+		for _, m := range s.Movies {
+			if strings.Contains(strings.ToLower(m.Title), query) {
+				results = append(results, map[string]any{"id": m.Id, "title": m.Title, "release_date": m.ReleaseDate})
+			}
+		}
+		writeResults(w, results)
+	case len(parts) == 2 && parts[0] == "search" && parts[1] == "tv":
+		var results []map[string]any
+		for _, show := range s.Shows {
+			if strings.Contains(strings.ToLower(show.Name), query) {
+				results = append(results, map[string]any{"id": show.Id, "name": show.Name, "first_air_date": show.FirstAirDate})
+			}
+		}
+		writeResults(w, results)
+	case len(parts) == 2 && parts[0] == "movie":
+		for _, m := range s.Movies {
+			if strconv.Itoa(m.Id) == parts[1] {
+				json.NewEncoder(w).Encode(map[string]any{
+					"id": m.Id, "title": m.Title, "release_date": m.ReleaseDate, "runtime": m.Runtime, "imdb_id": m.ImdbId,
+				})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case len(parts) == 3 && parts[0] == "tv" && parts[2] == "external_ids":
+		for _, show := range s.Shows {
+			if strconv.Itoa(show.Id) == parts[1] {
+				json.NewEncoder(w).Encode(map[string]any{"id": show.Id, "imdb_id": show.ImdbId})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case len(parts) == 4 && parts[0] == "tv" && parts[2] == "season":
+		season, _ := strconv.Atoi(parts[3])
+		for _, show := range s.Shows {
+			if episodes, ok := show.Seasons[season]; ok && strconv.Itoa(show.Id) == parts[1] {
+				var list []map[string]any
+				for _, e := range episodes {
+					list = append(list, map[string]any{"episode_number": e.Number, "season_number": season, "name": e.Name, "runtime": e.Runtime})
+				}
+				json.NewEncoder(w).Encode(map[string]any{"season_number": season, "episodes": list})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeResults(w http.ResponseWriter, results []map[string]any) {
+	if results == nil {
+		results = []map[string]any{}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"page": 1, "results": results, "total_results": len(results)})
+}
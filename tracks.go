@@ -0,0 +1,334 @@
+package makemkv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LangOriginal in a TrackRule stands for the original language of the title.
+const LangOriginal = "original"
+
+// TrackRule matches audio or subtitle streams. Zero fields match anything.
+type TrackRule struct {
+	// any ISO 639 code or name of the language, or LangOriginal
+	Lang string
+	// codec id prefixes, "A_DTS" matches every DTS variant
+	Codecs   []string
+	Lossless bool
+	// only the core of a lossless stream, like the AC3 of TrueHD
+	Core        bool
+	MinChannels int
+	// prefer the matching stream with the most channels
+	HighestChannels bool
+	// only forced subtitles
+	Forced bool
+	// only commentary streams, which the rule picks even when the
+	// preference does not keep commentary
+	Commentary bool
+	// never commentary streams, even when the preference keeps them
+	NoCommentary bool
+	// pick every matching stream, not only the best
+	All bool
+}
+
+// TrackPreference is an ordered list of rules per stream type. Rules are
+// applied in order and each picks its best matching stream, unless a stream
+// it matches was already picked, so "eng lossless" followed by "eng" picks
+// a lossless track if there is one and any english track otherwise.
+type TrackPreference struct {
+	Audio     []TrackRule
+	Subtitles []TrackRule
+	// keep commentary tracks, they are never picked otherwise
+	Commentary bool
+	// code of the LangOriginal language, the language of the first audio
+	// stream if empty
+	OriginalLang string
+}
+
+type ChosenStream struct {
+	Id int
+	// index of the rule that picked the stream
+	Rule   int
+	Reason string
+}
+
+type TrackSelection struct {
+	Audio     []ChosenStream
+	Subtitles []ChosenStream
+}
+
+// Ids returns the ids of all chosen streams, audio first.
+func (s TrackSelection) Ids() []int {
+	var ids []int
+	for _, c := range s.Audio {
+		ids = append(ids, c.Id)
+	}
+	for _, c := range s.Subtitles {
+		ids = append(ids, c.Id)
+	}
+	return ids
+}
+
+// track is what the rules look at, for audio and subtitle streams alike.
+type track struct {
+	id          int
+	name        string
+	lang        Language
+	codecId     string
+	codecShort  string
+	codec       Codec
+	channels    int
+	streamFlags int
+}
+
+func (t track) lossless() bool {
+	return t.streamFlags&StreamFlagCoreAudio == 0 && t.codec.Lossless()
+}
+
+func (t track) commentary() bool {
+	return t.streamFlags&(StreamFlagDirectorsComments|StreamFlagAlternateDirectorsComments) != 0 ||
+		strings.Contains(strings.ToLower(t.name), "comment")
+}
+
+func (t track) forced() bool {
+	return t.streamFlags&StreamFlagForcedSubtitles != 0
+}
+
+func (t track) String() string {
+	s := string(t.lang)
+	if s == "" {
+		s = string(LanguageUndetermined)
+	}
+	if t.codecShort != "" {
+		s += " " + t.codecShort
+	} else if t.codecId != "" {
+		s += " " + t.codecId
+	}
+	if t.channels > 0 {
+		s += " " + strconv.Itoa(t.channels) + "ch"
+	}
+	if t.forced() {
+		s += " forced"
+	}
+	return s
+}
+
+func (r TrackRule) matches(t track, original Language) bool {
+	if r.Lang == LangOriginal {
+		if original == "" || t.lang != original {
+			return false
+		}
+	} else if r.Lang != "" && !t.lang.Matches(r.Lang) {
+		return false
+	}
+	if len(r.Codecs) > 0 {
+		ok := false
+		for _, c := range r.Codecs {
+			ok = ok || strings.HasPrefix(t.codecId, c)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return (!r.Lossless || t.lossless()) &&
+		(!r.Core || t.streamFlags&StreamFlagCoreAudio != 0) &&
+		t.channels >= r.MinChannels &&
+		(!r.Forced || t.forced()) &&
+		(!r.Commentary || t.commentary()) &&
+		(!r.NoCommentary || !t.commentary())
+}
+
+// better tells if a is a better pick for the rule than b.
+func (r TrackRule) better(a track, b track) bool {
+	if r.HighestChannels && a.channels != b.channels {
+		return a.channels > b.channels
+	}
+	// a full subtitle stream over the forced one, unless asked for
+	if !r.Forced && a.forced() != b.forced() {
+		return !a.forced()
+	}
+	return false
+}
+
+func (r TrackRule) String() string {
+	var words []string
+	if r.Lang != "" {
+		words = append(words, r.Lang)
+	} else {
+		words = append(words, "any")
+	}
+	for _, c := range r.Codecs {
+		words = append(words, c)
+	}
+	if r.Lossless {
+		words = append(words, "lossless")
+	}
+	if r.Core {
+		words = append(words, "core")
+	}
+	if r.MinChannels > 0 {
+		words = append(words, strconv.Itoa(r.MinChannels)+"ch")
+	}
+	if r.HighestChannels {
+		words = append(words, "highest")
+	}
+	if r.Forced {
+		words = append(words, "forced")
+	}
+	if r.Commentary {
+		words = append(words, "commentary")
+	}
+	if r.NoCommentary {
+		words = append(words, "no commentary")
+	}
+	if r.All {
+		words = append(words, "all")
+	}
+	return strings.Join(words, " ")
+}
+
+// SelectTracks applies the preference to the streams of title.
+func SelectTracks(title TitleInfo, pref TrackPreference) TrackSelection {
+	audio := make([]track, len(title.AudioStreams))
+	for i, a := range title.AudioStreams {
+		lang := a.Language
+		if lang == "" {
+			lang = streamLanguage(a.LangCode, a.LangName)
+		}
+		audio[i] = track{a.Id, a.Name, lang, a.CodecId, a.CodecShort, a.Codec, a.ChannelCount, a.StreamFlags}
+	}
+	subtitles := make([]track, len(title.SubtitleStreams))
+	for i, s := range title.SubtitleStreams {
+		lang := s.Language
+		if lang == "" {
+			lang = streamLanguage(s.LangCode, s.LangName)
+		}
+		subtitles[i] = track{s.Id, s.Name, lang, s.CodecId, s.CodecShort, s.Codec, 0, s.StreamFlags}
+	}
+
+	original := streamLanguage(pref.OriginalLang, "")
+	if pref.OriginalLang == "" && len(audio) > 0 {
+		original = audio[0].lang
+	}
+	return TrackSelection{
+		Audio:     selectTracks(audio, pref.Audio, pref.Commentary, original),
+		Subtitles: selectTracks(subtitles, pref.Subtitles, pref.Commentary, original),
+	}
+}
+
+func selectTracks(tracks []track, rules []TrackRule, commentary bool, original Language) []ChosenStream {
+	var chosen []ChosenStream
+	picked := make(map[int]bool)
+	for i, rule := range rules {
+		var matches []track
+		satisfied := false
+		for _, t := range tracks {
+			if (!commentary && !rule.Commentary && t.commentary()) || !rule.matches(t, original) {
+				continue
+			}
+			if picked[t.id] {
+				satisfied = true
+			} else {
+				matches = append(matches, t)
+			}
+		}
+		if len(matches) == 0 || (satisfied && !rule.All) {
+			continue
+		}
+		if !rule.All {
+			best := matches[0]
+			for _, t := range matches[1:] {
+				if rule.better(t, best) {
+					best = t
+				}
+			}
+			matches = []track{best}
+		}
+		for _, t := range matches {
+			picked[t.id] = true
+			chosen = append(chosen, ChosenStream{
+				Id:     t.id,
+				Rule:   i,
+				Reason: fmt.Sprintf("%s: rule %d (%s)", t, i+1, rule),
+			})
+		}
+	}
+	return chosen
+}
+
+var trackCodecs = map[string]string{
+	"ac3":    "A_AC3",
+	"eac3":   "A_EAC3",
+	"dts":    "A_DTS",
+	"truehd": "A_TRUEHD",
+	"flac":   "A_FLAC",
+	"lpcm":   "A_LPCM",
+	"aac":    "A_AAC",
+	"mp2":    "A_MPEG/L2",
+	"mp3":    "A_MPEG/L3",
+	"pgs":    "S_HDMV/PGS",
+	"vobsub": "S_VOBSUB",
+}
+
+// ParseTrackRules parses rules like "eng lossless highest, then eng ac3
+// core, then original". Each rule is a list of words: a language code or
+// name as taken by ParseLanguage, "original" or "any", a codec (ac3, eac3,
+// dts, truehd, flac, lpcm, aac, mp2, mp3, pgs, vobsub), "lossless", "core",
+// "highest", "forced", "commentary", "no commentary", "all" or a minimum
+// channel count like "6ch".
+func ParseTrackRules(s string) ([]TrackRule, error) {
+	var rules []TrackRule
+	for _, part := range strings.Split(s, ",") {
+		words := strings.Fields(strings.ToLower(part))
+		if len(words) > 0 && words[0] == "then" {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			continue
+		}
+		var rule TrackRule
+		for i := 0; i < len(words); i++ {
+			w := words[i]
+			if codec, ok := trackCodecs[w]; ok {
+				rule.Codecs = append(rule.Codecs, codec)
+				continue
+			}
+			switch {
+			case w == "any":
+			case w == LangOriginal:
+				rule.Lang = LangOriginal
+			case w == "lossless":
+				rule.Lossless = true
+			case w == "core":
+				rule.Core = true
+			case w == "highest":
+				rule.HighestChannels = true
+			case w == "forced":
+				rule.Forced = true
+			case w == "all":
+				rule.All = true
+			case w == "commentary":
+				rule.Commentary = true
+			// before the languages, "no" is Norwegian
+			case w == "no" && i+1 < len(words) && words[i+1] == "commentary":
+				rule.NoCommentary = true
+				i++
+			case strings.HasSuffix(w, "ch"):
+				n, err := strconv.Atoi(strings.TrimSuffix(w, "ch"))
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid channel count %q", w)
+				}
+				rule.MinChannels = n
+			default:
+				lang, ok := ParseLanguage(w)
+				if !ok {
+					return nil, fmt.Errorf("invalid track rule word %q", w)
+				}
+				rule.Lang = string(lang)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
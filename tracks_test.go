@@ -0,0 +1,78 @@
+package makemkv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectTracks(t *testing.T) {
+	title := TitleInfo{
+		AudioStreams: []AudioStreamInfo{
+			{Id: 1, LangCode: "eng", CodecId: "A_TRUEHD", CodecShort: "TrueHD", Codec: CodecTrueHD, ChannelCount: 8, StreamFlags: StreamFlagHasCoreAudio},
+			{Id: 2, LangCode: "eng", CodecId: "A_AC3", CodecShort: "DD", Codec: CodecAC3, ChannelCount: 6, StreamFlags: StreamFlagCoreAudio | StreamFlagDerivedStream},
+			{Id: 3, LangCode: "eng", CodecId: "A_DTS", CodecShort: "DTS-HD MA", Codec: CodecDTSHD, ChannelCount: 6},
+			{Id: 4, LangCode: "eng", CodecId: "A_AC3", CodecShort: "DD", Codec: CodecAC3, ChannelCount: 2, StreamFlags: StreamFlagDirectorsComments},
+			{Id: 5, LangCode: "fra", CodecId: "A_AC3", CodecShort: "DD", Codec: CodecAC3, ChannelCount: 6},
+		},
+		SubtitleStreams: []SubtitleStreamInfo{
+			{Id: 6, LangCode: "eng", CodecId: "S_HDMV/PGS", Codec: CodecPGS, StreamFlags: StreamFlagForcedSubtitles | StreamFlagDerivedStream},
+			{Id: 7, LangCode: "eng", CodecId: "S_HDMV/PGS"},
+			{Id: 8, LangCode: "fra", CodecId: "S_HDMV/PGS"},
+		},
+	}
+
+	audio, err := ParseTrackRules("eng lossless highest, then eng ac3 core, then original")
+	assert.Nil(t, err)
+	subtitles, err := ParseTrackRules("eng forced")
+	assert.Nil(t, err)
+	pref := TrackPreference{Audio: audio, Subtitles: subtitles}
+
+	s := SelectTracks(title, pref)
+	assert.Equal(t, []int{1, 2, 6}, s.Ids())
+	assert.Equal(t, "eng TrueHD 8ch: rule 1 (eng lossless highest)", s.Audio[0].Reason)
+	assert.Equal(t, 1, s.Audio[1].Rule)
+
+	// only the commentary is english, so the original language rule picks fra
+	title.AudioStreams = title.AudioStreams[3:]
+	pref.OriginalLang = "fra"
+	pref.Subtitles = []TrackRule{{Lang: "eng"}, {All: true, Lang: "fra"}}
+	s = SelectTracks(title, pref)
+	assert.Equal(t, []int{5, 7, 8}, s.Ids())
+
+	pref.Commentary = true
+	pref.Audio = []TrackRule{{Lang: "eng"}}
+	assert.Equal(t, 4, SelectTracks(title, pref).Audio[0].Id)
+
+	_, err = ParseTrackRules("eng 0ch")
+	assert.NotNil(t, err)
+	_, err = ParseTrackRules("eng xyzzy")
+	assert.NotNil(t, err)
+}
+
+func TestParseTrackRules(t *testing.T) {
+	rules, err := ParseTrackRules("english lossless, then en commentary, then no commentary, then no")
+	assert.Nil(t, err)
+	assert.Equal(t, []TrackRule{
+		{Lang: "eng", Lossless: true},
+		{Lang: "eng", Commentary: true},
+		{NoCommentary: true},
+		{Lang: "nor"},
+	}, rules)
+	assert.Equal(t, "any no commentary", rules[2].String())
+}
+
+func TestSelectCommentary(t *testing.T) {
+	title := TitleInfo{AudioStreams: []AudioStreamInfo{
+		{Id: 1, LangCode: "eng", Codec: CodecAC3, ChannelCount: 6},
+		{Id: 2, LangCode: "eng", Codec: CodecAC3, ChannelCount: 2, StreamFlags: StreamFlagDirectorsComments},
+	}}
+	// a commentary rule picks the commentary without keeping every one
+	rules, err := ParseTrackRules("eng, eng commentary")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, SelectTracks(title, TrackPreference{Audio: rules}).Ids())
+
+	rules, err = ParseTrackRules("eng no commentary all")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1}, SelectTracks(title, TrackPreference{Audio: rules, Commentary: true}).Ids())
+}
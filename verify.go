@@ -0,0 +1,158 @@
+package makemkv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aravance/go-makemkv/matroska"
+)
+
+// how far the file duration may be off from the scan before it is reported
+const durationTolerance = 2 * time.Second
+
+type Discrepancy struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (d Discrepancy) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", d.Field, d.Expected, d.Actual)
+}
+
+type VerificationError struct {
+	Path          string
+	Discrepancies []Discrepancy
+}
+
+func (e *VerificationError) Error() string {
+	parts := make([]string, len(e.Discrepancies))
+	for i, d := range e.Discrepancies {
+		parts[i] = d.String()
+	}
+	return "makemkv: " + e.Path + " does not match scan: " + strings.Join(parts, "; ")
+}
+
+// Verify reads the produced file and compares it with the title it was
+// ripped from. Any mismatch is returned both as a list and as a
+// *VerificationError.
+func Verify(output OutputFile, title TitleInfo) ([]Discrepancy, error) {
+	if output.Path == "" {
+		return nil, fmt.Errorf("makemkv: title %d has no output file", output.TitleId)
+	}
+	file, err := matroska.Open(output.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancies := compareFile(file, title)
+	if len(discrepancies) > 0 {
+		return discrepancies, &VerificationError{Path: output.Path, Discrepancies: discrepancies}
+	}
+	return nil, nil
+}
+
+// VerifyResult verifies every successful output of result against info.
+func VerifyResult(result *MkvResult, info DiscInfo) error {
+	var failed []Discrepancy
+	for _, output := range result.Files {
+		if !output.Success {
+			continue
+		}
+		if output.TitleId < 0 || output.TitleId >= len(info.Titles) {
+			return fmt.Errorf("makemkv: no scan for title %d", output.TitleId)
+		}
+		discrepancies, err := Verify(output, info.Titles[output.TitleId])
+		if err != nil && discrepancies == nil {
+			return err
+		}
+		for _, d := range discrepancies {
+			d.Field = "title " + strconv.Itoa(output.TitleId) + " " + d.Field
+			failed = append(failed, d)
+		}
+	}
+	if len(failed) > 0 {
+		return &VerificationError{Path: result.Destination, Discrepancies: failed}
+	}
+	return nil
+}
+
+func compareFile(file *matroska.File, title TitleInfo) []Discrepancy {
+	var d []Discrepancy
+	if file.Truncated {
+		d = append(d, Discrepancy{"file", "complete", "truncated"})
+	}
+
+	diff := file.Duration - title.Duration
+	if title.Duration > 0 && (diff > durationTolerance || diff < -durationTolerance) {
+		d = append(d, Discrepancy{"duration", title.Duration.String(), file.Duration.Round(time.Second).String()})
+	}
+	if title.ChapterCount > 0 && len(file.Chapters) != title.ChapterCount {
+		d = append(d, Discrepancy{"chapters", strconv.Itoa(title.ChapterCount), strconv.Itoa(len(file.Chapters))})
+	}
+
+	var video, audio, subtitle []matroska.Track
+	for _, track := range file.Tracks {
+		switch track.Type {
+		case matroska.TrackVideo:
+			video = append(video, track)
+		case matroska.TrackAudio:
+			audio = append(audio, track)
+		case matroska.TrackSubtitle:
+			subtitle = append(subtitle, track)
+		}
+	}
+
+	if len(video) != len(title.VideoStreams) {
+		d = append(d, Discrepancy{"video tracks", strconv.Itoa(len(title.VideoStreams)), strconv.Itoa(len(video))})
+	} else {
+		for i, s := range title.VideoStreams {
+			d = compareTrack(d, "video track "+strconv.Itoa(i), s.Codec, s.CodecId, "", video[i])
+		}
+	}
+	if len(audio) != len(title.AudioStreams) {
+		d = append(d, Discrepancy{"audio tracks", strconv.Itoa(len(title.AudioStreams)), strconv.Itoa(len(audio))})
+	} else {
+		for i, s := range title.AudioStreams {
+			// a converted stream is only known by the codec it is written as
+			codecId := s.CodecId
+			if s.Converted() {
+				codecId = ""
+			}
+			d = compareTrack(d, "audio track "+strconv.Itoa(i), s.OutputCodec(), codecId, s.LangCode, audio[i])
+		}
+	}
+	if len(subtitle) != len(title.SubtitleStreams) {
+		d = append(d, Discrepancy{"subtitle tracks", strconv.Itoa(len(title.SubtitleStreams)), strconv.Itoa(len(subtitle))})
+	} else {
+		for i, s := range title.SubtitleStreams {
+			d = compareTrack(d, "subtitle track "+strconv.Itoa(i), s.Codec, s.CodecId, s.LangCode, subtitle[i])
+		}
+	}
+	return d
+}
+
+// Matroska has one codec id for these and their core
+var containerCodecs = map[Codec]Codec{
+	CodecAtmos: CodecTrueHD,
+	CodecDTSHD: CodecDTS,
+}
+
+// compareTrack checks the track against the codec the stream is written as.
+// Streams of an unknown codec are compared by their codec id, if any.
+func compareTrack(d []Discrepancy, field string, codec Codec, codecId string, langCode string, track matroska.Track) []Discrepancy {
+	if codec != CodecUnknown {
+		actual := ParseCodec(track.CodecId, "", "")
+		if actual != codec && actual != containerCodecs[codec] {
+			d = append(d, Discrepancy{field + " codec", codec.Name(), track.CodecId})
+		}
+	} else if codecId != "" && codecId != track.CodecId {
+		d = append(d, Discrepancy{field + " codec", codecId, track.CodecId})
+	}
+	if langCode != "" && langCode != "und" && langCode != track.Language {
+		d = append(d, Discrepancy{field + " language", langCode, track.Language})
+	}
+	return d
+}
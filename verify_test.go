@@ -0,0 +1,82 @@
+package makemkv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aravance/go-makemkv/matroska"
+	"github.com/stretchr/testify/assert"
+)
+
+func verifyTitle() TitleInfo {
+	return TitleInfo{
+		Duration:     90 * time.Minute,
+		ChapterCount: 2,
+		VideoStreams: []VideoStreamInfo{{CodecId: "V_MPEG4/ISO/AVC", Codec: CodecH264}},
+		AudioStreams: []AudioStreamInfo{
+			{CodecId: "A_TRUEHD", CodecShort: "TrueHD Atmos", Codec: CodecAtmos, LangCode: "eng"},
+			// LPCM that makemkvcon writes as FLAC
+			{CodecId: "A_LPCM", CodecShort: "LPCM", Codec: CodecLPCM, LangCode: "fra", Output: AudioOutput{CodecShort: "FLAC"}},
+		},
+		SubtitleStreams: []SubtitleStreamInfo{{CodecId: "S_HDMV/PGS", Codec: CodecPGS, LangCode: "und"}},
+	}
+}
+
+func verifyFile() *matroska.File {
+	return &matroska.File{
+		Duration: 90*time.Minute + time.Second,
+		Chapters: make([]matroska.Chapter, 2),
+		Tracks: []matroska.Track{
+			{Type: matroska.TrackVideo, CodecId: "V_MPEG4/ISO/AVC", Language: "und"},
+			{Type: matroska.TrackAudio, CodecId: "A_TRUEHD", Language: "eng"},
+			{Type: matroska.TrackAudio, CodecId: "A_FLAC", Language: "fra"},
+			{Type: matroska.TrackSubtitle, CodecId: "S_HDMV/PGS", Language: "eng"},
+		},
+	}
+}
+
+func TestCompareFile(t *testing.T) {
+	assert.Empty(t, compareFile(verifyFile(), verifyTitle()))
+
+	file := verifyFile()
+	file.Truncated = true
+	file.Duration = 80 * time.Minute
+	file.Chapters = nil
+	file.Tracks[2].CodecId = "A_PCM/INT/LIT"
+	file.Tracks[1].Language = "ger"
+	assert.Equal(t, []Discrepancy{
+		{"file", "complete", "truncated"},
+		{"duration", "1h30m0s", "1h20m0s"},
+		{"chapters", "2", "0"},
+		{"audio track 0 language", "eng", "ger"},
+		{"audio track 1 codec", "FLAC", "A_PCM/INT/LIT"},
+	}, compareFile(file, verifyTitle()))
+
+	file = verifyFile()
+	file.Tracks = file.Tracks[:3]
+	assert.Equal(t, []Discrepancy{{"subtitle tracks", "1", "0"}}, compareFile(file, verifyTitle()))
+
+	// an unknown codec is compared by its id
+	title := verifyTitle()
+	title.SubtitleStreams[0] = SubtitleStreamInfo{CodecId: "S_TEXT/UTF8"}
+	assert.Equal(t, []Discrepancy{{"subtitle track 0 codec", "S_TEXT/UTF8", "S_HDMV/PGS"}}, compareFile(verifyFile(), title))
+}
+
+func TestVerify(t *testing.T) {
+	_, err := Verify(OutputFile{TitleId: 1}, verifyTitle())
+	assert.NotNil(t, err)
+
+	path := filepath.Join(t.TempDir(), "title_t00.mkv")
+	assert.Nil(t, os.WriteFile(path, []byte("not a matroska file"), 0644))
+	discrepancies, err := Verify(OutputFile{Path: path}, verifyTitle())
+	assert.Nil(t, discrepancies)
+	assert.NotNil(t, err)
+
+	err = VerifyResult(&MkvResult{Files: []OutputFile{{TitleId: 3, Path: path, Success: true}}}, DiscInfo{})
+	assert.NotNil(t, err)
+	var verifyErr *VerificationError
+	assert.False(t, errors.As(err, &verifyErr))
+}
@@ -0,0 +1,184 @@
+package makemkv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type VersionInfo struct {
+	Name     string
+	Version  string
+	Major    int
+	Minor    int
+	Patch    int
+	Platform string
+	Build    string
+}
+
+func (v VersionInfo) Less(other VersionInfo) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v VersionInfo) String() string {
+	return v.Version
+}
+
+type LicenseInfo struct {
+	Version VersionInfo
+	// KeyTypeBeta or KeyTypeRegistered for the key in settings.conf,
+	// KeyTypeEvaluation while makemkvcon runs in its evaluation period
+	KeyType string
+	// end of the evaluation period, zero if makemkvcon did not print one
+	KeyExpiration time.Time
+	// the evaluation message as printed, like "Evaluation version, 25 day(s)
+	// out of 30 remaining"
+	EvalState     string
+	LatestVersion string
+	// makemkvcon printed PROT_DEMO_KEY_EXPIRED: the evaluation period or the
+	// beta key ran out, or the version is too old to use
+	ProgExpired bool
+}
+
+const (
+	KeyTypeEvaluation = "evaluation"
+	KeyTypeBeta       = "beta"
+	KeyTypeRegistered = "registered"
+)
+
+func (l LicenseInfo) Expired() bool {
+	return l.ExpiredAt(time.Now())
+}
+
+func (l LicenseInfo) ExpiredAt(t time.Time) bool {
+	if l.ProgExpired {
+		return true
+	}
+	return !l.KeyExpiration.IsZero() && t.After(l.KeyExpiration)
+}
+
+func Version(ctx context.Context, opts MkvOptions) (*VersionInfo, error) {
+	license, err := License(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &license.Version, nil
+}
+
+// License asks makemkvcon to open a drive index that cannot exist, which
+// makes it print its banner and licensing messages without touching a disc.
+// makemkvcon does not print the key it runs with, the key type comes from
+// settings.conf. Only opts.Executable is used.
+func License(ctx context.Context, opts MkvOptions) (*LicenseInfo, error) {
+	cmd := exec.CommandContext(ctx, opts.executable(), "-r", "--cache=1", "info", "disc:9999")
+	// makemkvcon exits non-zero since the drive does not exist, the output
+	// is all we are interested in
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(out) == 0 && err != nil {
+		return nil, err
+	}
+
+	license := parseLicense(bufio.NewScanner(bytes.NewReader(out)), time.Now())
+	if license.Version.Version == "" {
+		return nil, fmt.Errorf("makemkv: version banner not found in makemkvcon output")
+	}
+	if path, err := DefaultSettingsPath(); err == nil {
+		if settings, err := LoadSettings(path); err == nil {
+			if key, ok := settings.Get(SettingKey); ok && keyType(key) != "" {
+				license.KeyType = keyType(key)
+			}
+		}
+	}
+	return &license, nil
+}
+
+// keyType tells the kind of a key by its prefix, T- for the public beta key
+// and M- for a purchased one.
+func keyType(key string) string {
+	switch {
+	case strings.HasPrefix(key, "T-"):
+		return KeyTypeBeta
+	case strings.HasPrefix(key, "M-"):
+		return KeyTypeRegistered
+	}
+	return ""
+}
+
+var (
+	evalRegex          = regexp.MustCompile(`(?i)evaluation version, (\d+) day\(s\) out of (\d+) remaining`)
+	latestVersionRegex = regexp.MustCompile(`(?i)new version (\d+\.\d+\.\d+)`)
+)
+
+// parseLicense reads the startup messages of makemkvcon. The evaluation and
+// update messages are recognized by their text, their codes are not part of
+// apdefs.h.
+func parseLicense(scanner *bufio.Scanner, now time.Time) LicenseInfo {
+	var license LicenseInfo
+	for scanner.Scan() {
+		prefix, content, found := strings.Cut(scanner.Text(), ":")
+		if !found || prefix != "MSG" {
+			continue
+		}
+		msg, ok := parseMessage(content)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case msg.Code == msgAppStarted:
+			banner := msg.Text
+			if len(msg.Params) > 0 {
+				banner = msg.Params[0]
+			}
+			license.Version, _ = parseVersionBanner(banner)
+		case msg.Code == msgProtDemoKeyExpired:
+			license.ProgExpired = true
+		case evalRegex.MatchString(msg.Text):
+			m := evalRegex.FindStringSubmatch(msg.Text)
+			days, _ := strconv.Atoi(m[1])
+			y, mo, d := now.Date()
+			license.KeyType = KeyTypeEvaluation
+			license.EvalState = msg.Text
+			license.KeyExpiration = time.Date(y, mo, d+days, 0, 0, 0, 0, now.Location())
+		case latestVersionRegex.MatchString(msg.Text):
+			license.LatestVersion = latestVersionRegex.FindStringSubmatch(msg.Text)[1]
+		}
+	}
+	return license
+}
+
+// "MakeMKV v1.17.6 linux(x64-release)"
+func parseVersionBanner(banner string) (VersionInfo, bool) {
+	banner = strings.TrimSuffix(strings.TrimSpace(banner), " started")
+	fields := strings.Fields(banner)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], "v") {
+		return VersionInfo{}, false
+	}
+
+	v := VersionInfo{
+		Name:    fields[0],
+		Version: strings.TrimPrefix(fields[1], "v"),
+	}
+	fmt.Sscanf(v.Version, "%d.%d.%d", &v.Major, &v.Minor, &v.Patch)
+	if len(fields) > 2 {
+		platform, build, _ := strings.Cut(fields[2], "(")
+		v.Platform = platform
+		v.Build = strings.TrimSuffix(build, ")")
+	}
+	return v, true
+}
@@ -0,0 +1,69 @@
+package makemkv
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMessage(t *testing.T) {
+	msg, ok := parseMessage(`3025,16777216,3,"Title #00003.mpls has length of 8 seconds, skipped","Title #%1 has length of %2 seconds, skipped","00003.mpls","8","3600"`)
+	assert.True(t, ok)
+	assert.Equal(t, 3025, msg.Code)
+	assert.Equal(t, 16777216, msg.Flags)
+	assert.Equal(t, "Title #00003.mpls has length of 8 seconds, skipped", msg.Text)
+	assert.Equal(t, "Title #%1 has length of %2 seconds, skipped", msg.Format)
+	assert.Equal(t, []string{"00003.mpls", "8", "3600"}, msg.Params)
+
+	_, ok = parseMessage(`garbage`)
+	assert.False(t, ok)
+}
+
+func TestParseLicense(t *testing.T) {
+	now := time.Date(2024, 3, 6, 15, 4, 5, 0, time.Local)
+	license := parseLicense(bufio.NewScanner(strings.NewReader(licenseInput)), now)
+	assert.Equal(t, "MakeMKV", license.Version.Name)
+	assert.Equal(t, "1.17.6", license.Version.Version)
+	assert.Equal(t, 1, license.Version.Major)
+	assert.Equal(t, 17, license.Version.Minor)
+	assert.Equal(t, 6, license.Version.Patch)
+	assert.Equal(t, "linux", license.Version.Platform)
+	assert.Equal(t, "x64-release", license.Version.Build)
+	assert.Equal(t, "1.17.7", license.LatestVersion)
+	assert.Equal(t, KeyTypeEvaluation, license.KeyType)
+	assert.Equal(t, "Evaluation version, 25 day(s) out of 30 remaining", license.EvalState)
+	assert.False(t, license.ProgExpired)
+
+	expiry := time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local)
+	assert.Equal(t, expiry, license.KeyExpiration)
+	assert.False(t, license.ExpiredAt(expiry.Add(-time.Hour)))
+	assert.True(t, license.ExpiredAt(expiry.Add(time.Hour)))
+
+	license = parseLicense(bufio.NewScanner(strings.NewReader(expiredInput)), now)
+	assert.True(t, license.ProgExpired)
+	assert.True(t, license.Expired())
+	assert.Equal(t, "", license.KeyType)
+	assert.True(t, license.KeyExpiration.IsZero())
+
+	assert.Equal(t, KeyTypeBeta, keyType("T-abc"))
+	assert.Equal(t, KeyTypeRegistered, keyType("M-abc"))
+	assert.Equal(t, "", keyType(""))
+}
+
+// startup messages of makemkvcon -r info disc:9999, an evaluation copy with
+// an update available and one whose beta key ran out
+const licenseInput = `MSG:1005,0,1,"MakeMKV v1.17.6 linux(x64-release) started","%1 started","MakeMKV v1.17.6 linux(x64-release)"
+MSG:5075,0,0,"The new version 1.17.7 is available for download at http://www.makemkv.com/download/","The new version %1 is available for download at %2","1.17.7","http://www.makemkv.com/download/"
+MSG:5050,0,0,"Evaluation version, 25 day(s) out of 30 remaining","Evaluation version, %1 day(s) out of %2 remaining","25","30"
+MSG:5010,0,0,"Failed to open disc","Failed to open disc"
+TCOUNT:0
+`
+
+const expiredInput = `MSG:1005,0,1,"MakeMKV v1.17.6 linux(x64-release) started","%1 started","MakeMKV v1.17.6 linux(x64-release)"
+MSG:5021,260,1,"This application version is too old.  Please download the latest version at http://www.makemkv.com/ or enter a registration key to continue using the current version.","This application version is too old.  Please download the latest version at http://www.makemkv.com/ or enter a registration key to continue using the current version."
+MSG:5010,0,0,"Failed to open disc","Failed to open disc"
+TCOUNT:0
+`
@@ -0,0 +1,185 @@
+package makemkv
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rational is a ratio like a 16:9 display aspect or a 24000/1001 frame rate.
+type Rational struct {
+	Num int
+	Den int
+}
+
+func (r Rational) IsZero() bool {
+	return r.Num == 0 || r.Den == 0
+}
+
+func (r Rational) Float() float64 {
+	if r.Den == 0 {
+		return 0
+	}
+	return float64(r.Num) / float64(r.Den)
+}
+
+func (r Rational) String() string {
+	if r.Den == 1 {
+		return strconv.Itoa(r.Num)
+	}
+	return strconv.Itoa(r.Num) + "/" + strconv.Itoa(r.Den)
+}
+
+// NTSC rates that makemkvcon prints rounded
+var ntscRates = map[string]Rational{
+	"23.976": {24000, 1001},
+	"29.97":  {30000, 1001},
+	"59.94":  {60000, 1001},
+	"119.88": {120000, 1001},
+}
+
+// ParseRational parses "16:9", "24000/1001", "25", "29.97" and makemkvcon's
+// "23.976 (24000/1001)". Rounded NTSC rates become their exact ratio.
+func ParseRational(s string) (Rational, bool) {
+	s = strings.TrimSpace(s)
+	if i, j := strings.Index(s, "("), strings.LastIndex(s, ")"); i >= 0 && j > i {
+		s = s[i+1 : j]
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "i"), "p")
+	if i := strings.IndexAny(s, ":/"); i > 0 {
+		num, err1 := strconv.Atoi(s[:i])
+		den, err2 := strconv.Atoi(s[i+1:])
+		if err1 != nil || err2 != nil || num <= 0 || den <= 0 {
+			return Rational{}, false
+		}
+		return Rational{num, den}, true
+	}
+	if r, ok := ntscRates[s]; ok {
+		return r, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 || math.IsInf(f, 0) {
+		return Rational{}, false
+	}
+	num, den := int(math.Round(f*1000)), 1000
+	d := gcd(num, den)
+	return Rational{num / d, den / d}, true
+}
+
+func gcd(a int, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+type Resolution string
+
+const (
+	ResolutionUnknown Resolution = ""
+	ResolutionSD      Resolution = "SD"
+	ResolutionHD      Resolution = "HD"
+	ResolutionUHD     Resolution = "UHD"
+)
+
+func ClassifyResolution(width int, height int) Resolution {
+	switch {
+	case width <= 0 || height <= 0:
+		return ResolutionUnknown
+	case width >= 3840 || height >= 2160:
+		return ResolutionUHD
+	case width >= 1280 || height >= 720:
+		return ResolutionHD
+	default:
+		return ResolutionSD
+	}
+}
+
+// AtLeast tells if r is as high as min. ResolutionUnknown is lower than
+// everything else.
+func (r Resolution) AtLeast(min Resolution) bool {
+	rank := map[Resolution]int{ResolutionSD: 1, ResolutionHD: 2, ResolutionUHD: 3}
+	return rank[r] >= rank[min]
+}
+
+type HDRFormat string
+
+const (
+	HDRNone        HDRFormat = ""
+	HDR10          HDRFormat = "HDR10"
+	HDR10Plus      HDRFormat = "HDR10+"
+	HDRDolbyVision HDRFormat = "Dolby Vision"
+	HDRHLG         HDRFormat = "HLG"
+)
+
+var (
+	videoSizeRegex   = regexp.MustCompile(`^(\d+)\s*x\s*(\d+)\s*([ip]?)`)
+	dolbyVisionRegex = regexp.MustCompile(`(?i)dolby\s*vision|\bDV\b|\bDoVi\b`)
+)
+
+// parseVideoSize parses "1920x1080" and the "1920x1080i" some discs have.
+func parseVideoSize(s string) (width int, height int, interlaced bool) {
+	m := videoSizeRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, 0, false
+	}
+	width, _ = strconv.Atoi(m[1])
+	height, _ = strconv.Atoi(m[2])
+	return width, height, m[3] == "i"
+}
+
+// detectHDR looks for the HDR format in the stream texts makemkvcon shows,
+// it has no attribute of its own.
+func detectHDR(texts ...string) HDRFormat {
+	s := strings.Join(texts, " ")
+	switch {
+	case dolbyVisionRegex.MatchString(s):
+		return HDRDolbyVision
+	case strings.Contains(s, "HDR10+"), strings.Contains(s, "HDR10Plus"):
+		return HDR10Plus
+	case strings.Contains(s, "HDR"), strings.Contains(s, "SMPTE ST 2084"):
+		return HDR10
+	case strings.Contains(s, "HLG"):
+		return HDRHLG
+	}
+	return HDRNone
+}
+
+func (v VideoStreamInfo) Resolution() Resolution {
+	return ClassifyResolution(v.Width, v.Height)
+}
+
+// Resolution is the resolution of the first video stream, the main video.
+func (t TitleInfo) Resolution() Resolution {
+	if len(t.VideoStreams) == 0 {
+		return ResolutionUnknown
+	}
+	return t.VideoStreams[0].Resolution()
+}
+
+// HDR is the HDR format of the title. A Dolby Vision enhancement layer is
+// its own video stream, so the format of any stream counts.
+func (t TitleInfo) HDR() HDRFormat {
+	hdr := HDRNone
+	for _, v := range t.VideoStreams {
+		if v.HDR == HDRDolbyVision {
+			return v.HDR
+		}
+		if hdr == HDRNone {
+			hdr = v.HDR
+		}
+	}
+	return hdr
+}
+
+// FilterResolution returns the titles with a resolution of at least min.
+func FilterResolution(titles []TitleInfo, min Resolution) []TitleInfo {
+	var filtered []TitleInfo
+	for _, t := range titles {
+		if t.Resolution().AtLeast(min) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
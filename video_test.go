@@ -0,0 +1,53 @@
+package makemkv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRational(t *testing.T) {
+	tests := map[string]Rational{
+		"16:9":                {16, 9},
+		"23.976 (24000/1001)": {24000, 1001},
+		"29.97":               {30000, 1001},
+		"25":                  {25, 1},
+		"50i":                 {50, 1},
+		"12.5":                {25, 2},
+	}
+	for s, want := range tests {
+		r, ok := ParseRational(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, want, r, s)
+	}
+	_, ok := ParseRational("")
+	assert.False(t, ok)
+	_, ok = ParseRational("0:9")
+	assert.False(t, ok)
+	assert.Equal(t, "24000/1001", Rational{24000, 1001}.String())
+}
+
+func TestVideoProperties(t *testing.T) {
+	v := &VideoStreamInfo{}
+	v.setVideoSize("720x576i")
+	v.setFrameRate("25")
+	assert.Equal(t, 720, v.Width)
+	assert.True(t, v.Interlaced)
+	assert.Equal(t, ResolutionSD, v.Resolution())
+
+	assert.Equal(t, HDRDolbyVision, detectHDR("Dolby Vision EL", "MpegH HEVC Main10@L5.1"))
+	assert.Equal(t, HDR10Plus, detectHDR("HDR10+"))
+	assert.Equal(t, HDR10, detectHDR("", "MpegH HEVC Main10@L5.1 HDR10"))
+	assert.Equal(t, HDRNone, detectHDR("", "Mpeg4 AVC High@L4.1"))
+
+	titles := []TitleInfo{
+		{Id: 0, VideoStreams: []VideoStreamInfo{{Width: 3840, Height: 2160, HDR: HDR10}, {Width: 1920, Height: 1080, HDR: HDRDolbyVision}}},
+		{Id: 1, VideoStreams: []VideoStreamInfo{{Width: 1920, Height: 1080}}},
+		{Id: 2, VideoStreams: []VideoStreamInfo{{Width: 720, Height: 480}}},
+		{Id: 3},
+	}
+	assert.Equal(t, HDRDolbyVision, titles[0].HDR())
+	assert.Equal(t, 2, len(FilterResolution(titles, ResolutionHD)))
+	assert.Equal(t, 3, len(FilterResolution(titles, ResolutionSD)))
+	assert.Equal(t, 4, len(FilterResolution(titles, ResolutionUnknown)))
+}